@@ -0,0 +1,127 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Batch holds several endpoints signed by the Casper API with a single endpointauth request,
+// so refreshing several endpoints at once costs one Casper request.
+// The Casper API does not document signing several endpoints at once: they are asked for as a comma
+// separated endpoints claim, and every endpoint its response leaves out is signed on its own when it is
+// requested, as if it was requested without a Batch.
+// A signature held by a Batch is used once, or for as long as its cache_millis allows,
+// and only with the auth token it was signed for. Retries, replays after logging in again
+// and stale signatures are signed again by the Casper API like Casper.Call does.
+// A Batch is safe for concurrent use.
+type Batch struct {
+	c         *Casper
+	endpoints []string
+	data      SnapchatRequestModel
+	authToken string
+	signedAt  time.Time
+
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// AuthorizeEndpoints signs every endpoint in endpoints with a single request to the Casper API
+// and returns a Batch for requesting them from Snapchat.
+func (c *Casper) AuthorizeEndpoints(endpoints ...string) (*Batch, error) {
+	return c.AuthorizeEndpointsContext(context.Background(), endpoints...)
+}
+
+// AuthorizeEndpointsContext is like AuthorizeEndpoints but carries ctx to every request it makes.
+func (c *Casper) AuthorizeEndpointsContext(ctx context.Context, endpoints ...string) (*Batch, error) {
+	if len(endpoints) == 0 {
+		return nil, Error{Err: casperEndpointError, Reason: errors.New("no endpoints to authorize")}
+	}
+	err := c.checkToken()
+	if err != nil {
+		return nil, err
+	}
+	_, authToken := c.account()
+	signedAt := time.Now()
+	data, err := c.authorize(ctx, endpoints...)
+	if err != nil {
+		return nil, err
+	}
+	return &Batch{
+		c:         c,
+		endpoints: endpoints,
+		data:      data,
+		authToken: authToken,
+		signedAt:  signedAt,
+		used:      make(map[string]bool),
+	}, nil
+}
+
+// Call performs a request to endpoint like Casper.Call, using the signature held by b while it is fresh.
+// Requesting an endpoint b was not authorized for fails with ErrEndpoint.
+func (b *Batch) Call(ctx context.Context, endpoint string, extra map[string]string) (*Response, error) {
+	if !b.has(endpoint) {
+		return nil, Error{Err: casperEndpointError, Reason: fmt.Errorf("endpoint %s is not in the batch", endpoint)}
+	}
+	first := true
+	sign := func(ctx context.Context, endpoint string) (Options, error) {
+		if first {
+			first = false
+			if scEndpoint, ok := b.take(endpoint); ok {
+				return b.c.endpointOptions(endpoint, scEndpoint), nil
+			}
+		}
+		return b.c.options(ctx, endpoint)
+	}
+	return b.c.callSigned(ctx, endpoint, sign, extraParams(extra))
+}
+
+// Do requests every endpoint of b in the order they were authorized, without extra parameters,
+// and returns their responses by endpoint. It stops at the first failed request.
+func (b *Batch) Do(ctx context.Context) (map[string]*Response, error) {
+	responses := make(map[string]*Response, len(b.endpoints))
+	for _, endpoint := range b.endpoints {
+		res, err := b.Call(ctx, endpoint, nil)
+		if err != nil {
+			return responses, err
+		}
+		responses[endpoint] = res
+	}
+	return responses, nil
+}
+
+// has reports whether endpoint is one of the endpoints of b.
+func (b *Batch) has(endpoint string) bool {
+	for _, e := range b.endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// take returns the signature b holds for endpoint if it is still fresh, and marks it used.
+// A signature is stale once the auth token changed, once its cache_millis ran out or,
+// when the Casper API sent no cache_millis, once it was used.
+func (b *Batch) take(endpoint string) (SnapchatEndpoint, bool) {
+	scEndpoint, err := b.data.Endpoint(endpoint)
+	if err != nil {
+		return SnapchatEndpoint{}, false
+	}
+	if _, authToken := b.c.account(); authToken != b.authToken {
+		return SnapchatEndpoint{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if scEndpoint.CacheMillis > 0 {
+		expires := b.signedAt.Add(time.Duration(scEndpoint.CacheMillis) * time.Millisecond)
+		return scEndpoint, time.Now().Before(expires)
+	}
+	if b.used[endpoint] {
+		return SnapchatEndpoint{}, false
+	}
+	b.used[endpoint] = true
+	return scEndpoint, true
+}
@@ -0,0 +1,150 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Test Batch.
+func TestBatch(t *testing.T) {
+	endpoints := []string{"/loq/all_updates", "/bq/stories", "/bq/bests", "/bq/suggest_friend"}
+
+	var casperRequests int32
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		if req.FormValue("req_token") != "test_req_token"+req.URL.Path {
+			t.Errorf("Batch.Do(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", endpoints, "test_req_token"+req.URL.Path, req.FormValue("req_token"))
+		}
+		rw.Write([]byte(req.URL.Path))
+	})
+	defer closeServers()
+	casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&casperRequests, 1)
+		claims := testClaims(req)
+		var entries []string
+		for _, endpoint := range strings.Split(claims["endpoints"].(string), ",") {
+			entries = append(entries, fmt.Sprintf(`{"endpoint": "%s", "params": {"req_token": "test_req_token%s"}}`, endpoint, endpoint))
+		}
+		fmt.Fprintf(rw, `{"code": 200, "endpoints": [%s]}`, strings.Join(entries, ","))
+	}))
+	defer casperServer.Close()
+	testCasperClient.CasperURL = casperServer.URL
+
+	batch, err := testCasperClient.AuthorizeEndpoints(endpoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses, err := batch.Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range endpoints {
+		if res := responses[endpoint]; res == nil || string(res.Body) != endpoint {
+			t.Errorf("Batch.Do(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", endpoints, endpoint, res)
+		}
+	}
+	if result := atomic.LoadInt32(&casperRequests); result != 1 {
+		t.Errorf("Batch.Do(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", endpoints, 1, result)
+	}
+
+	if _, err := batch.Call(context.Background(), "/ph/logout", nil); !errors.Is(err, ErrEndpoint) {
+		t.Errorf("Batch.Call(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "/ph/logout", ErrEndpoint, err)
+	}
+}
+
+// Test Batch signing stale entries again.
+func TestBatchStale(t *testing.T) {
+	var paramTests = []struct {
+		cacheMillis    int
+		failures       int32
+		calls          int
+		casperRequests int32
+	}{
+		{0, 0, 1, 1},
+		{0, 0, 2, 2},
+		{60000, 0, 2, 1},
+		{60000, 1, 1, 2},
+	}
+
+	for _, test := range paramTests {
+		var casperRequests, failures int32
+		var reqTokens []string
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			reqTokens = append(reqTokens, req.FormValue("req_token"))
+			if atomic.AddInt32(&failures, 1) <= test.failures {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rw.Write([]byte(`{}`))
+		})
+		casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			n := atomic.AddInt32(&casperRequests, 1)
+			claims := testClaims(req)
+			endpoint, _ := claims["endpoint"].(string)
+			if endpoint == "" {
+				endpoint, _ = claims["endpoints"].(string)
+			}
+			fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"endpoint": "%s", "cache_millis": %d, "params": {"req_token": "test_req_token_%d"}}]}`, endpoint, test.cacheMillis, n)
+		}))
+		testCasperClient.CasperURL = casperServer.URL
+		testCasperClient.Retry = &RetryPolicy{MaxAttempts: 2}
+
+		batch, err := testCasperClient.AuthorizeEndpoints("/loq/all_updates")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < test.calls; i++ {
+			if _, err := batch.Call(context.Background(), "/loq/all_updates", nil); err != nil {
+				t.Errorf("Batch.Call(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "/loq/all_updates", nil, err)
+			}
+		}
+		if result := atomic.LoadInt32(&casperRequests); result != test.casperRequests {
+			t.Errorf("Batch.Call(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", "/loq/all_updates", test.casperRequests, result)
+		}
+		if test.failures > 0 && reqTokens[0] == reqTokens[1] {
+			t.Errorf("Batch.Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/loq/all_updates", "fresh req_token", reqTokens)
+		}
+		casperServer.Close()
+		closeServers()
+	}
+}
+
+// Test Batch with a Casper API signing only single endpoints.
+func TestBatchFallback(t *testing.T) {
+	endpoints := []string{"/loq/all_updates", "/bq/stories"}
+
+	var casperRequests int32
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.Path))
+	})
+	defer closeServers()
+	casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&casperRequests, 1)
+		endpoint, _ := testClaims(req)["endpoint"].(string)
+		fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"endpoint": "%s", "params": {"req_token": "test_req_token"}}]}`, endpoint)
+	}))
+	defer casperServer.Close()
+	testCasperClient.CasperURL = casperServer.URL
+
+	batch, err := testCasperClient.AuthorizeEndpoints(endpoints...)
+	if err != nil {
+		t.Fatalf("AuthorizeEndpoints(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", endpoints, "<nil>", err)
+	}
+	responses, err := batch.Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range endpoints {
+		if res := responses[endpoint]; res == nil || string(res.Body) != endpoint {
+			t.Errorf("Batch.Do(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", endpoints, endpoint, res)
+		}
+	}
+	if result := atomic.LoadInt32(&casperRequests); result != 3 {
+		t.Errorf("Batch.Do(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", endpoints, 3, result)
+	}
+}
@@ -2,15 +2,23 @@
 package casper
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
@@ -22,19 +30,35 @@ const (
 	SnapchatBaseURL = "https://app.snapchat.com"
 )
 
-// Casper common variables and error variables
-var (
-	casperParseError      = Error{Err: "casper: CasperParseError"}
-	casperHTTPError       = Error{Err: "casper: CasperHTTPError"}
-	casperSignatureError  = Error{Err: "casper: CasperSignatureError"}
-	casperAuthError       = Error{Err: "casper: CasperAuthError"}
-	casperDeprecatedError = Error{Err: "casper: CasperDeprecatedError"}
+// Snapchat media types.
+const (
+	MediaImage        = 0
+	MediaVideo        = 1
+	MediaVideoNoAudio = 2
+)
 
-	captchaID = ""
-	status    = 0
+// Default timeouts of the HTTP client shared by requests of a Casper client.
+const (
+	DefaultDialTimeout           = 30 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultResponseHeaderTimeout = 30 * time.Second
+	DefaultIdleConnTimeout       = 90 * time.Second
 )
 
 // Casper holds credentials to be used when connecting to the Casper API.
+//
+// HTTPClient is optional, when it is nil a client with the default timeouts is
+// created on first use and reused for every Casper and Snapchat request.
+// CasperURL and SnapchatURL are optional and default to CasperBaseURL and SnapchatBaseURL.
+// Retry is optional, failed requests are not retried when it is nil.
+// CasperLimiter and SnapchatLimiter are optional and limit requests to the Casper API and to Snapchat.
+// Share a CasperLimiter between clients using the same API key.
+// Endpoints signed by the Casper API are cached for as long as its cache_millis allows.
+// When Strict is true, responses holding top-level fields their model does not know fail with ErrParse.
+// Drift is optional, when it is set every decoded response is compared to its model, see DriftReport.
+// When AutoLogin is true, a request failing because the auth token expired logs in again once
+// with the credentials from Credentials, or Username and Password when it is nil, and is then replayed.
+// Username, Password and AuthToken may be set before a client is used, the client updates them itself afterwards.
 type Casper struct {
 	APIKey      string
 	APISecret   string
@@ -44,6 +68,34 @@ type Casper struct {
 	Debug       bool
 	ProxyURL    *url.URL
 	ProjectName string
+	HTTPClient  *http.Client
+	CasperURL   string
+	SnapchatURL string
+	Retry       *RetryPolicy
+	Strict      bool
+	Drift       *DriftReport
+
+	CasperLimiter   *RateLimiter
+	SnapchatLimiter *RateLimiter
+
+	AutoLogin   bool
+	Credentials CredentialProvider
+
+	mu          sync.Mutex
+	client      *http.Client
+	clientProxy *url.URL
+	endpoints   endpointCache
+	loginMu     sync.Mutex
+
+	// authMu guards Username, Password, AuthToken and device once the client is in use.
+	authMu sync.RWMutex
+	device device
+}
+
+// ExpireEndpointCache drops every endpointauth entry cached by c, so the next request to each endpoint
+// is signed by the Casper API again.
+func (c *Casper) ExpireEndpointCache() {
+	c.endpoints.clear()
 }
 
 // Snapchat holds the credentials needed to pass on data to Snapchat's Servers.
@@ -59,48 +111,52 @@ type Captcha struct {
 
 // Options holds data about parameters of a Casper request.
 type Options struct {
-	Headers map[string]string
-	Params  map[string]string
+	Endpoint string
+	Headers  map[string]string
+	Params   map[string]string
+}
+
+// Response holds the raw body and metadata of a Snapchat response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
 }
 
 // Client is an interface for methods that wish to communicate with Casper and Snapchat.
 type Client interface {
-	performRequest(method string, endpoint string, params map[string]string, headers map[string]string) ([]byte, error)
+	performRequest(ctx context.Context, method string, endpoint string, params map[string]string, headers map[string]string) ([]byte, error)
 }
 
-// Error handles errors returned by casper methods.
-type Error struct {
-	Err    string
-	Reason error
+// performRequest is a template that creates HTTP requests with proxy and debug support.
+func (s *Snapchat) performRequest(ctx context.Context, method string, endpoint string, params map[string]string, headers map[string]string) ([]byte, error) {
+	res, err := s.do(ctx, method, endpoint, params, headers)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
 }
 
-// Error is a function which CasperError satisfies.
-// It returns a properly formatted error message when an error occurs.
-func (e Error) Error() string {
-	return fmt.Sprintf("%s\nReason: %s", e.Err, e.Reason.Error())
+// do performs a HTTP request to Snapchat bound to ctx and returns the Response.
+func (s *Snapchat) do(ctx context.Context, method string, endpoint string, params map[string]string, headers map[string]string) (*Response, error) {
+	req, err := s.newRequest(ctx, method, endpoint, params, headers)
+	if err != nil {
+		return nil, err
+	}
+	return s.roundTrip(req)
 }
 
-// performRequest is a template that creates HTTP requests with proxy and debug support.
-func (s *Snapchat) performRequest(method string, endpoint string, params map[string]string, headers map[string]string) ([]byte, error) {
-	var tr *http.Transport
+// newRequest creates a form encoded HTTP request to Snapchat bound to ctx.
+func (s *Snapchat) newRequest(ctx context.Context, method string, endpoint string, params map[string]string, headers map[string]string) (*http.Request, error) {
 	var snapchatForm url.Values
 	var req *http.Request
+	var err error
 
+	baseURL := s.CasperClient.snapchatURL()
 	if s.CasperClient.Debug == true {
-		fmt.Printf(method+"\t%s\n", SnapchatBaseURL+endpoint)
-	}
-
-	tr = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
-	}
-
-	if s.CasperClient.ProxyURL != nil {
-		tr.Proxy = http.ProxyURL(s.CasperClient.ProxyURL)
-		tr.TLSClientConfig.InsecureSkipVerify = true
+		fmt.Printf(method+"\t%s\n", baseURL+endpoint)
 	}
 
-	client := &http.Client{Transport: tr}
-
 	if params != nil {
 		snapchatForm = url.Values{}
 		for k, v := range params {
@@ -113,10 +169,14 @@ func (s *Snapchat) performRequest(method string, endpoint string, params map[str
 	}
 
 	if method == "GET" {
-		req, _ = http.NewRequest(method, SnapchatBaseURL+endpoint, nil)
+		req, err = http.NewRequest(method, baseURL+endpoint, nil)
 	} else {
-		req, _ = http.NewRequest(method, SnapchatBaseURL+endpoint, strings.NewReader(snapchatForm.Encode()))
+		req, err = http.NewRequest(method, baseURL+endpoint, strings.NewReader(snapchatForm.Encode()))
+	}
+	if err != nil {
+		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	if headers != nil {
 		for k, v := range headers {
@@ -124,21 +184,17 @@ func (s *Snapchat) performRequest(method string, endpoint string, params map[str
 		}
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
 
-	res, err := client.Do(req)
+// roundTrip sends the prepared Snapchat request req and returns the Response.
+func (s *Snapchat) roundTrip(req *http.Request) (*Response, error) {
+	res, err := s.open(req)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
-	if endpoint == "/bq/get_captcha" {
-		captchaID = res.Header["Content-Disposition"][0][20:]
-	}
-
-	if endpoint == "/ph/logout" || endpoint == "/loq/send" || endpoint == "/bq/delete_story" {
-		status = res.StatusCode
-	}
-
 	parsedData, err := parseBody(res)
 	if err != nil {
 		return nil, err
@@ -148,23 +204,135 @@ func (s *Snapchat) performRequest(method string, endpoint string, params map[str
 		fmt.Println(string(parsedData))
 	}
 
-	return parsedData, nil
+	if err := snapchatResponseError(res, parsedData); err != nil {
+		s.CasperClient.SnapchatLimiter.Delay(retryAfter(err))
+		return nil, err
+	}
+
+	response := &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       parsedData,
+	}
+	return response, nil
+}
+
+// open sends the prepared Snapchat request req and returns the successful *http.Response
+// with its body left unread, so large media can be streamed. The caller must close the body.
+// A Snapchat error payload sent with a 2xx status code fails like any other Snapchat error.
+func (s *Snapchat) open(req *http.Request) (*http.Response, error) {
+	if err := s.CasperClient.SnapchatLimiter.Wait(req.Context()); err != nil {
+		return nil, Error{Err: casperHTTPError, Reason: err}
+	}
+
+	client := s.CasperClient.httpClient()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, Error{Err: casperHTTPError, Reason: err}
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		parsedData, err := parseBody(res)
+		if err != nil {
+			return nil, err
+		}
+		err = snapchatResponseError(res, parsedData)
+		s.CasperClient.SnapchatLimiter.Delay(retryAfter(err))
+		return nil, err
+	}
+	// Blob endpoints answer with a JSON error payload instead of the media when they fail.
+	body := bufio.NewReader(res.Body)
+	if first, _ := body.Peek(1); len(first) == 1 && first[0] == '{' {
+		parsedData, err := ioutil.ReadAll(body)
+		res.Body.Close()
+		if err != nil {
+			return nil, Error{Err: casperParseError, Reason: err}
+		}
+		if err := snapchatResponseError(res, parsedData); err != nil {
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(parsedData))
+		return res, nil
+	}
+	res.Body = bufferedBody{body, res.Body}
+	return res, nil
+}
+
+// bufferedBody is a response body read through a buffer.
+type bufferedBody struct {
+	io.Reader
+	io.Closer
 }
 
 // Login performs a login request to Snapchat and returns an Updates model.
 func (c *Casper) Login(username string, password string) (Updates, error) {
-	model, err := c.login(username, password)
+	return c.LoginContext(context.Background(), username, password)
+}
+
+// LoginContext is like Login but carries ctx to every request it makes.
+func (c *Casper) LoginContext(ctx context.Context, username string, password string) (Updates, error) {
+	scdata, err := c.authenticate(ctx, username, password)
 	if err != nil {
 		return Updates{}, err
 	}
+	// Save only once the user has logged in.
+	c.authMu.Lock()
+	c.Password = password
+	c.authMu.Unlock()
+	return scdata, nil
+}
+
+// authenticate logs in to Snapchat as username and saves username along with its auth token.
+// The password is not saved.
+func (c *Casper) authenticate(ctx context.Context, username string, password string) (Updates, error) {
+	var data []byte
+	err := c.retry(ctx, func() error {
+		var err error
+		data, err = c.snapchatLogin(ctx, username, password)
+		return err
+	})
+	if err != nil {
+		return Updates{}, err
+	}
+	var scdata Updates
+	if err := c.decode("/loq/login", data, &scdata); err != nil {
+		return Updates{}, err
+	}
+	if scdata.UpdatesResponse.AuthToken == "" {
+		return Updates{}, Error{Err: casperAuthError, Reason: errors.New("login response has no auth token")}
+	}
+	// Save the username along with its auth token, so they always belong to the same account.
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.Username = username
+	c.AuthToken = scdata.UpdatesResponse.AuthToken
+	return scdata, nil
+}
+
+// snapchatLogin signs a login with the Casper API and sends it to Snapchat.
+func (c *Casper) snapchatLogin(ctx context.Context, username string, password string) ([]byte, error) {
+	model, err := c.login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	// Log in as the device the account first logged in with, so Snapchat does not see a new device.
+	c.authMu.Lock()
+	if c.device.UUID == "" {
+		c.device = device{
+			UUID:        model.Headers.XSnapchatUUID,
+			ClientToken: model.Headers.XSnapchatClientToken,
+		}
+	}
+	d := c.device
+	c.authMu.Unlock()
 	headers := map[string]string{
 		"Accept":                       model.Headers.Accept,
 		"Accept-Language":              model.Headers.AcceptLanguage,
 		"Accept-Locale":                model.Headers.AcceptLocale,
 		"User-Agent":                   model.Headers.UserAgent,
 		"X-Snapchat-Client-Auth-Token": model.Headers.XSnapchatClientAuthToken,
-		"X-Snapchat-Client-Token":      model.Headers.XSnapchatClientToken,
-		"X-Snapchat-UUID":              model.Headers.XSnapchatUUID,
+		"X-Snapchat-Client-Token":      d.ClientToken,
+		"X-Snapchat-UUID":              d.UUID,
 	}
 	params := map[string]string{
 		"confirm_reactivation": model.Params.ConfirmReactivation,
@@ -187,65 +355,39 @@ func (c *Casper) Login(username string, password string) (Updates, error) {
 	s := Snapchat{
 		CasperClient: c,
 	}
-	data, err := s.performRequest("POST", "/loq/login", params, headers)
+	data, err := s.performRequest(ctx, "POST", "/loq/login", params, headers)
 	if err != nil {
-		return Updates{}, err
-	}
-	// Save only once the user has logged in.
-	if c.Username == "" || c.Password == "" {
-		c.Username = username
-		c.Password = password
+		return nil, err
 	}
-	var scdata Updates
-	json.Unmarshal(data, &scdata)
-
-	// Save auth token.
-	c.AuthToken = scdata.UpdatesResponse.AuthToken
-	return scdata, nil
+	return data, nil
 }
 
 // Updates fetches updates from Snapchat and returns an Updates model.
 func (c *Casper) Updates() (Updates, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Updates{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/loq/all_updates",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Updates{}, err
-	}
-	data, err := c.endpointAuth(token)
+	return c.UpdatesContext(context.Background())
+}
+
+// UpdatesContext is like Updates but carries ctx to every request it makes.
+func (c *Casper) UpdatesContext(ctx context.Context) (Updates, error) {
+	res, err := c.Call(ctx, "/loq/all_updates", nil)
 	if err != nil {
 		return Updates{}, err
 	}
-	updateEndpoint := data.Endpoints[0]   // update endpoint data
-	endpoint := updateEndpoint.Endpoint   // /loq/updates
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":  updateEndpoint.Params.Username,
-		"req_token": updateEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(updateEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
-	if err != nil {
+	var updateData Updates
+	if err := c.decode("/loq/all_updates", res.Body, &updateData); err != nil {
 		return Updates{}, err
 	}
-	var updateData Updates
-	json.Unmarshal(scdata, &updateData)
-	return updateData, err
+	return updateData, nil
 }
 
 // Register registers a account from Snapchat and returns an Register model.
 func (c *Casper) Register(username, password, email, birthday string) (Register, error) {
-	model, err := c.login(username, password)
+	return c.RegisterContext(context.Background(), username, password, email, birthday)
+}
+
+// RegisterContext is like Register but carries ctx to every request it makes.
+func (c *Casper) RegisterContext(ctx context.Context, username, password, email, birthday string) (Register, error) {
+	model, err := c.login(ctx, username, password)
 	if err != nil {
 		return Register{}, err
 	}
@@ -266,299 +408,178 @@ func (c *Casper) Register(username, password, email, birthday string) (Register,
 	s := Snapchat{
 		CasperClient: c,
 	}
-	data, err := s.performRequest("POST", "/loq/register", params, headers)
+	data, err := s.performRequest(ctx, "POST", "/loq/register", params, headers)
 	if err != nil {
 		return Register{}, err
 	}
 	var registerData Register
-	json.Unmarshal(data, &registerData)
+	if err := c.decode("/loq/register", data, &registerData); err != nil {
+		return Register{}, err
+	}
 
 	// Save auth token.
+	c.authMu.Lock()
 	c.AuthToken = registerData.AuthToken
 	c.Password = password
 	c.Username = username
+	c.authMu.Unlock()
 	return registerData, nil
 }
 
 // GetCaptcha fetches a captcha puzzle from snapchat.
 func (c *Casper) GetCaptcha() (Captcha, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Captcha{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/get_captcha",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Captcha{}, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return Captcha{}, err
-	}
-	captchaEndpoint := data.Endpoints[0]  // update endpoint data
-	endpoint := captchaEndpoint.Endpoint  // /loq/get_captcha
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":  captchaEndpoint.Params.Username,
-		"req_token": captchaEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(captchaEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	return c.GetCaptchaContext(context.Background())
+}
+
+// GetCaptchaContext is like GetCaptcha but carries ctx to every request it makes.
+func (c *Casper) GetCaptchaContext(ctx context.Context) (Captcha, error) {
+	res, err := c.Call(ctx, "/bq/get_captcha", nil)
 	if err != nil {
 		return Captcha{}, err
 	}
 	captcha := Captcha{
-		ID:   captchaID,
-		Data: scdata,
+		ID:   captchaIDFromHeader(res.Header),
+		Data: res.Body,
 	}
 	return captcha, nil
 }
 
 // SolveCaptcha solves the captcha puzzle from snapchat.
 func (c *Casper) SolveCaptcha(captchaID, solution string) (string, error) {
-	err := c.checkToken()
-	if err != nil {
-		return "", err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/solve_captcha",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return "", err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return "", err
-	}
+	return c.SolveCaptchaContext(context.Background(), captchaID, solution)
+}
+
+// SolveCaptchaContext is like SolveCaptcha but carries ctx to every request it makes.
+func (c *Casper) SolveCaptchaContext(ctx context.Context, captchaID, solution string) (string, error) {
 	solution = strings.Replace(solution, "\n", "", -1) // Get rid of those pesky newlines.
-	solvecaptchaEndpoint := data.Endpoints[0]          // update endpoint data
-	endpoint := solvecaptchaEndpoint.Endpoint          // /loq/solve_captcha
-	headers := c.setSnapchatHeaders(data)              // headers
-	params := map[string]string{
+	res, err := c.Call(ctx, "/bq/solve_captcha", map[string]string{
 		"captcha_solution": solution,
 		"captcha_id":       captchaID,
-		"username":         solvecaptchaEndpoint.Params.Username,
-		"req_token":        solvecaptchaEndpoint.Params.ReqToken,
-		"timestamp":        strconv.FormatInt(solvecaptchaEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	})
 	if err != nil {
 		return "", err
 	}
-	return string(scdata), err
+	return string(res.Body), nil
 }
 
 // VerifyPhoneNumber sends a phone number to Snapchat for verification.
-func (c *Casper) VerifyPhoneNumber(phoneNumber, countryCode string) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/phone_verify",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	phoneVerifyEndpoint := data.Endpoints[0] // update endpoint data
-	endpoint := phoneVerifyEndpoint.Endpoint // /loq/phone_verify
-	headers := c.setSnapchatHeaders(data)    // headers
-	params := map[string]string{
+func (c *Casper) VerifyPhoneNumber(phoneNumber, countryCode string) (PhoneVerification, error) {
+	return c.VerifyPhoneNumberContext(context.Background(), phoneNumber, countryCode)
+}
+
+// VerifyPhoneNumberContext is like VerifyPhoneNumber but carries ctx to every request it makes.
+func (c *Casper) VerifyPhoneNumberContext(ctx context.Context, phoneNumber, countryCode string) (PhoneVerification, error) {
+	res, err := c.Call(ctx, "/bq/phone_verify", map[string]string{
 		"phoneNumber":      phoneNumber,
 		"action":           "updatePhoneNumber",
 		"skipConfirmation": "true",
 		"countryCode":      countryCode,
-		"username":         phoneVerifyEndpoint.Params.Username,
-		"req_token":        phoneVerifyEndpoint.Params.ReqToken,
-		"timestamp":        strconv.FormatInt(phoneVerifyEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	})
 	if err != nil {
-		return nil, err
+		return PhoneVerification{}, err
+	}
+	var verifyData PhoneVerification
+	if err := c.decode("/bq/phone_verify", res.Body, &verifyData); err != nil {
+		return PhoneVerification{}, err
 	}
-	return scdata, err
+	verifyData.Raw = res.Body
+	return verifyData, nil
 }
 
 // SendSMSCode sends an SMS code to Snapchat.
-func (c *Casper) SendSMSCode(code string) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/phone_verify",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
+func (c *Casper) SendSMSCode(code string) (PhoneVerification, error) {
+	return c.SendSMSCodeContext(context.Background(), code)
+}
+
+// SendSMSCodeContext is like SendSMSCode but carries ctx to every request it makes.
+func (c *Casper) SendSMSCodeContext(ctx context.Context, code string) (PhoneVerification, error) {
 	code = strings.Replace(code, "\n", "", -1) // Get rid of those pesky newlines.
-	phoneVerifyEndpoint := data.Endpoints[0]   // update endpoint data
-	endpoint := phoneVerifyEndpoint.Endpoint   // /loq/phone_verify
-	headers := c.setSnapchatHeaders(data)      // headers
-	params := map[string]string{
-		"action":    "verifyPhoneNumber",
-		"code":      code,
-		"type":      "DEFAULT_TYPE",
-		"username":  phoneVerifyEndpoint.Params.Username,
-		"req_token": phoneVerifyEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(phoneVerifyEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	res, err := c.Call(ctx, "/bq/phone_verify", map[string]string{
+		"action": "verifyPhoneNumber",
+		"code":   code,
+		"type":   "DEFAULT_TYPE",
+	})
 	if err != nil {
-		return nil, err
+		return PhoneVerification{}, err
+	}
+	var verifyData PhoneVerification
+	if err := c.decode("/bq/phone_verify", res.Body, &verifyData); err != nil {
+		return PhoneVerification{}, err
 	}
-	return scdata, err
+	verifyData.Raw = res.Body
+	return verifyData, nil
 }
 
 // IPRouting gets IP Routing URLs.
-func (c *Casper) IPRouting() ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/ip_routing",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	IProutingEndpoint := data.Endpoints[0] // update endpoint data
-	endpoint := IProutingEndpoint.Endpoint // /bq/ip_routing
-	headers := c.setSnapchatHeaders(data)  // headers
-	params := map[string]string{
-		"userId":             c.Username,
+func (c *Casper) IPRouting() (IPRouting, error) {
+	return c.IPRoutingContext(context.Background())
+}
+
+// IPRoutingContext is like IPRouting but carries ctx to every request it makes.
+func (c *Casper) IPRoutingContext(ctx context.Context) (IPRouting, error) {
+	username, _ := c.account()
+	res, err := c.Call(ctx, "/bq/ip_routing", map[string]string{
+		"userId":             username,
 		"currentUrlEntities": "",
-		"username":           IProutingEndpoint.Params.Username,
-		"req_token":          IProutingEndpoint.Params.ReqToken,
-		"timestamp":          strconv.FormatInt(IProutingEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	})
 	if err != nil {
-		return nil, err
+		return IPRouting{}, err
+	}
+	var routingData IPRouting
+	if err := c.decode("/bq/ip_routing", res.Body, &routingData); err != nil {
+		return IPRouting{}, err
 	}
-	return scdata, err
+	routingData.Raw = res.Body
+	return routingData, nil
 }
 
 // SuggestedFriends fetches all the Snapchat suggested friends.
-func (c *Casper) SuggestedFriends() ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/suggest_friend",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
+func (c *Casper) SuggestedFriends() (SuggestedFriends, error) {
+	return c.SuggestedFriendsContext(context.Background())
+}
+
+// SuggestedFriendsContext is like SuggestedFriends but carries ctx to every request it makes.
+func (c *Casper) SuggestedFriendsContext(ctx context.Context) (SuggestedFriends, error) {
+	res, err := c.Call(ctx, "/bq/suggest_friend", map[string]string{
+		"action": "list",
+	})
 	if err != nil {
-		return nil, err
+		return SuggestedFriends{}, err
 	}
-	suggestedFriendsEndpoint := data.Endpoints[0] // update endpoint data
-	endpoint := suggestedFriendsEndpoint.Endpoint // /bq/suggest_friend
-	headers := c.setSnapchatHeaders(data)         // headers
-	params := map[string]string{
-		"action":    "list",
-		"username":  suggestedFriendsEndpoint.Params.Username,
-		"req_token": suggestedFriendsEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(suggestedFriendsEndpoint.Params.Timestamp, 10),
+	var suggestedData SuggestedFriends
+	if err := c.decode("/bq/suggest_friend", res.Body, &suggestedData); err != nil {
+		return SuggestedFriends{}, err
 	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
-	if err != nil {
-		return nil, err
-	}
-	return scdata, err
+	suggestedData.Raw = res.Body
+	return suggestedData, nil
 }
 
 // LoadLensSchedule fetches the lens schedule for the authenticated account.
-func (c *Casper) LoadLensSchedule() ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/lens/load_schedule",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
+func (c *Casper) LoadLensSchedule() (LensSchedule, error) {
+	return c.LoadLensScheduleContext(context.Background())
+}
+
+// LoadLensScheduleContext is like LoadLensSchedule but carries ctx to every request it makes.
+func (c *Casper) LoadLensScheduleContext(ctx context.Context) (LensSchedule, error) {
+	res, err := c.Call(ctx, "/lens/load_schedule", nil)
 	if err != nil {
-		return nil, err
-	}
-	lensScheduleEndpoint := data.Endpoints[0] // update endpoint data
-	endpoint := lensScheduleEndpoint.Endpoint // /lens/load_schedule
-	headers := c.setSnapchatHeaders(data)     // headers
-	params := map[string]string{
-		"username":  lensScheduleEndpoint.Params.Username,
-		"req_token": lensScheduleEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(lensScheduleEndpoint.Params.Timestamp, 10),
+		return LensSchedule{}, err
 	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
-	if err != nil {
-		return nil, err
+	var lensData LensSchedule
+	if err := c.decode("/lens/load_schedule", res.Body, &lensData); err != nil {
+		return LensSchedule{}, err
 	}
-	return scdata, err
+	lensData.Raw = res.Body
+	return lensData, nil
 }
 
 // DiscoverChannels fetches Snapchat discover channels.
-func (c *Casper) DiscoverChannels() ([]byte, error) {
+func (c *Casper) DiscoverChannels() (Discover, error) {
+	return c.DiscoverChannelsContext(context.Background())
+}
+
+// DiscoverChannelsContext is like DiscoverChannels but carries ctx to every request it makes.
+func (c *Casper) DiscoverChannelsContext(ctx context.Context) (Discover, error) {
 	var endpoint = "/discover/channel_list?region=US&country=USA&version=1&language=en"
 	s := Snapchat{
 		CasperClient: c,
@@ -568,370 +589,248 @@ func (c *Casper) DiscoverChannels() ([]byte, error) {
 		"Accept-Locale":   "en_US",
 		"User-Agent":      "Snapchat/9.26.0.1 (iPhone6,1; iOS 9.0; gzip)",
 	}
-	scdata, err := s.performRequest("GET", endpoint, nil, headers)
+	var scdata []byte
+	err := c.retry(ctx, func() error {
+		var err error
+		scdata, err = s.performRequest(ctx, "GET", endpoint, nil, headers)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return Discover{}, err
+	}
+	var discoverData Discover
+	if err := c.decode(endpoint, scdata, &discoverData); err != nil {
+		return Discover{}, err
 	}
-	return scdata, err
+	discoverData.Raw = scdata
+	return discoverData, nil
 }
 
 // RegisterUsername registers a username from Snapchat and returns an Updates model.
 func (c *Casper) RegisterUsername(username string, email string) (Updates, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Updates{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/loq/register_username",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Updates{}, err
-	}
-	endpointData, err := c.endpointAuth(token)
-	if err != nil {
-		return Updates{}, err
-	}
-	registerUsernameEndpoint := endpointData.Endpoints[0] // register_username endpoint data
-	endpoint := registerUsernameEndpoint.Endpoint         // /loq/register_username
-	headers := c.setSnapchatHeaders(endpointData)         // headers
-	params := map[string]string{
-		"req_token":         registerUsernameEndpoint.Params.ReqToken,
-		"timestamp":         strconv.FormatInt(registerUsernameEndpoint.Params.Timestamp, 10),
+	return c.RegisterUsernameContext(context.Background(), username, email)
+}
+
+// RegisterUsernameContext is like RegisterUsername but carries ctx to every request it makes.
+func (c *Casper) RegisterUsernameContext(ctx context.Context, username string, email string) (Updates, error) {
+	res, err := c.Call(ctx, "/loq/register_username", map[string]string{
 		"username":          email,
 		"selected_username": username,
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	data, err := s.performRequest("POST", endpoint, params, headers)
+	})
 	if err != nil {
 		return Updates{}, err
 	}
 	var registerUsernameData Updates
-	json.Unmarshal(data, &registerUsernameData)
+	if err := c.decode("/loq/register_username", res.Body, &registerUsernameData); err != nil {
+		return Updates{}, err
+	}
 	return registerUsernameData, nil
 }
 
 // DownloadSnapTag fetches the authenticated users Snaptag.
-func (c *Casper) DownloadSnapTag(id, format string) ([]byte, error) {
-	err := c.checkToken()
+func (c *Casper) DownloadSnapTag(id, format string) (SnapTag, error) {
+	return c.DownloadSnapTagContext(context.Background(), id, format)
+}
+
+// DownloadSnapTagContext is like DownloadSnapTag but carries ctx to every request it makes.
+func (c *Casper) DownloadSnapTagContext(ctx context.Context, id, format string) (SnapTag, error) {
+	res, err := c.Call(ctx, "/bq/snaptag_download", map[string]string{
+		"type":    format,
+		"user_id": id,
+	})
 	if err != nil {
-		return nil, err
+		return SnapTag{}, err
 	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/snaptag_download",
+	var snapTagData SnapTag
+	if err := c.decode("/bq/snaptag_download", res.Body, &snapTagData); err != nil {
+		return SnapTag{}, err
 	}
-	token, err := c.signToken(jwtform)
+	snapTagData.Raw = res.Body
+	return snapTagData, nil
+}
+
+// Upload uploads media of mediaType to Snapchat and returns its media ID,
+// ready to be passed to Send or PostStory.
+// Snapchat expects media to be encrypted already, see the media package.
+func (c *Casper) Upload(media io.Reader, mediaType int) (string, error) {
+	return c.UploadContext(context.Background(), media, mediaType)
+}
+
+// UploadContext is like Upload but carries ctx to every request it makes.
+// Uploads are never retried, since media can only be read once.
+func (c *Casper) UploadContext(ctx context.Context, media io.Reader, mediaType int) (string, error) {
+	opts, err := c.options(ctx, "/ph/upload")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	data, err := c.endpointAuth(token)
+	mediaID, err := c.newMediaID()
 	if err != nil {
-		return nil, err
-	}
-	downloadSnaptagEndpoint := data.Endpoints[0] // update endpoint data
-	endpoint := downloadSnaptagEndpoint.Endpoint // /bq/snaptag_download
-	headers := c.setSnapchatHeaders(data)        // headers
-	params := map[string]string{
-		"type":      format,
-		"user_id":   id,
-		"username":  downloadSnaptagEndpoint.Params.Username,
-		"req_token": downloadSnaptagEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(downloadSnaptagEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
+		return "", err
 	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
-	if err != nil {
-		return nil, err
+	opts.Params["media_id"] = mediaID
+	opts.Params["type"] = strconv.Itoa(mediaType)
+
+	if c.Debug == true {
+		fmt.Printf("POST\t%s\n%s\n", c.snapchatURL()+opts.Endpoint, opts.Params)
 	}
-	return scdata, err
-}
 
-// Upload sends media to Snapchat.
-// TODO: Implement multipart requests instead of returning Options.
-func (c *Casper) Upload() (Options, error) {
-	err := c.checkToken()
+	body, contentType := multipartBody(opts.Params, "data", media)
+	req, err := http.NewRequest("POST", c.snapchatURL()+opts.Endpoint, body)
 	if err != nil {
-		return Options{}, err
+		body.Close()
+		return "", err
 	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/ph/upload",
+	req = req.WithContext(ctx)
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
 	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Options{}, err
+	req.Header.Set("Content-Type", contentType)
+
+	s := Snapchat{
+		CasperClient: c,
 	}
-	data, err := c.endpointAuth(token)
+	_, err = s.roundTrip(req)
+	body.Close()
 	if err != nil {
-		return Options{}, err
-	}
-	uploadEndpoint := data.Endpoints[0]   // upload endpoint data
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":  uploadEndpoint.Params.Username,
-		"req_token": uploadEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(uploadEndpoint.Params.Timestamp, 10),
-	}
-	options := Options{
-		headers,
-		params,
+		return "", err
 	}
-	return options, err
+	return mediaID, nil
 }
 
 // Send sends media to other Snapchat users.
-func (c *Casper) Send(mediaID string, recipients []string, time int) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
+func (c *Casper) Send(mediaID string, recipients []string, time int) (Sent, error) {
+	return c.SendContext(context.Background(), mediaID, recipients, time)
+}
+
+// SendContext is like Send but carries ctx to every request it makes.
+func (c *Casper) SendContext(ctx context.Context, mediaID string, recipients []string, time int) (Sent, error) {
+	return c.send(ctx, mediaID, recipients, time, false)
+}
+
+// SendZipped sends media uploaded as a zipped snap, holding the media and an overlay image, to other Snapchat users.
+// Zipped snaps can be created with media.Zip.
+func (c *Casper) SendZipped(mediaID string, recipients []string, time int) (Sent, error) {
+	return c.SendZippedContext(context.Background(), mediaID, recipients, time)
+}
+
+// SendZippedContext is like SendZipped but carries ctx to every request it makes.
+func (c *Casper) SendZippedContext(ctx context.Context, mediaID string, recipients []string, time int) (Sent, error) {
+	return c.send(ctx, mediaID, recipients, time, true)
+}
+
+// send sends the uploaded media mediaID to recipients, telling Snapchat whether it was uploaded zipped.
+func (c *Casper) send(ctx context.Context, mediaID string, recipients []string, time int, zipped bool) (Sent, error) {
 	rp, rperr := json.Marshal(recipients)
 	if rperr != nil {
-		return nil, rperr
+		return Sent{}, rperr
 	}
-	timeString := strconv.Itoa(int(time))
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/loq/send",
+	zippedParam := "0"
+	if zipped {
+		zippedParam = "1"
 	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	sendEndpoint := data.Endpoints[0]     // update endpoint data
-	endpoint := sendEndpoint.Endpoint     // /loq/send
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":            sendEndpoint.Params.Username,
-		"req_token":           sendEndpoint.Params.ReqToken,
-		"timestamp":           strconv.FormatInt(sendEndpoint.Params.Timestamp, 10),
+	res, err := c.Call(ctx, "/loq/send", map[string]string{
 		"media_id":            mediaID,
 		"recipients":          string(rp),
 		"reply":               "0",
-		"time":                timeString,
+		"time":                strconv.Itoa(time),
 		"country_code":        "US",
 		"camera_front_facing": "0",
-		"zipped":              "0",
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+		"zipped":              zippedParam,
+	})
 	if err != nil {
-		return nil, err
+		return Sent{}, err
+	}
+	if res.StatusCode != 200 {
+		return Sent{}, SnapchatError{StatusCode: res.StatusCode}
 	}
-	if status != 200 {
-		return nil, errors.New("snapchat: Something went wrong")
+	var sentData Sent
+	if err := c.decode("/loq/send", res.Body, &sentData); err != nil {
+		return Sent{}, err
 	}
-	return scdata, err
+	sentData.Raw = res.Body
+	return sentData, nil
 }
 
 // RetrySend retries to resend media to Snapchat users.
 // TODO: Implement multipart requests instead of returning Options.
 func (c *Casper) RetrySend() (Options, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Options{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/loq/retry",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Options{}, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return Options{}, err
-	}
-	uploadEndpoint := data.Endpoints[0]   // upload endpoint data
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":  uploadEndpoint.Params.Username,
-		"req_token": uploadEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(uploadEndpoint.Params.Timestamp, 10),
-	}
-	options := Options{
-		headers,
-		params,
-	}
-	return options, err
+	return c.RetrySendContext(context.Background())
+}
+
+// RetrySendContext is like RetrySend but carries ctx to every request it makes.
+func (c *Casper) RetrySendContext(ctx context.Context) (Options, error) {
+	return c.options(ctx, "/loq/retry")
 }
 
 // Stories fetches the current users Snapchat stories. Useful if you only want the Snapchat stories.
-// [Not working as of now. Just use /loq/all_updates instead]
 func (c *Casper) Stories() (Stories, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Stories{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/stories",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Stories{}, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return Stories{}, err
-	}
-	storiesEndpoint := data.Endpoints[1]  // upload endpoint data
-	endpoint := storiesEndpoint.Endpoint  // /bq/stories
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":  storiesEndpoint.Params.Username,
-		"req_token": storiesEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(storiesEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
-	if err != nil {
-		return Stories{}, err
-	}
-	var storiesData Stories
-	json.Unmarshal(scdata, &storiesData)
-	return storiesData, err
+	return c.StoriesContext(context.Background())
 }
-
-// PostStory sends a story to Snapchat.
-func (c *Casper) PostStory(mediaID string, caption string, time int, mediaType string) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/post_story",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	postStoryEndpoint := data.Endpoints[0] // upload endpoint data
-	endpoint := postStoryEndpoint.Endpoint // /bq/post_story
-	headers := c.setSnapchatHeaders(data)  // headers
-	params := map[string]string{
-		"camera_front_facing": strconv.FormatInt(0, 10),
-		"username":            postStoryEndpoint.Params.Username,
-		"req_token":           postStoryEndpoint.Params.ReqToken,
-		"media_id":            mediaID,
-		"client_id":           mediaID,
-		"type":                mediaType,
-		"caption":             caption,
-		"zipped":              strconv.FormatInt(0, 10),
-		"orientation":         strconv.FormatInt(0, 10),
-		"time":                strconv.FormatInt(int64(time), 10),
-		"story_timestamp":     strconv.FormatInt(postStoryEndpoint.Params.Timestamp, 10),
-		"timestamp":           strconv.FormatInt(postStoryEndpoint.Params.Timestamp, 10),
+
+// StoriesContext is like Stories but carries ctx to every request it makes.
+func (c *Casper) StoriesContext(ctx context.Context) (Stories, error) {
+	res, err := c.Call(ctx, "/bq/stories", nil)
+	if err != nil {
+		return Stories{}, err
 	}
-	s := Snapchat{
-		CasperClient: c,
+	var storiesData Stories
+	if err := c.decode("/bq/stories", res.Body, &storiesData); err != nil {
+		return Stories{}, err
 	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	return storiesData, nil
+}
+
+// PostStory sends a story to Snapchat.
+func (c *Casper) PostStory(mediaID string, caption string, time int, mediaType string) ([]byte, error) {
+	return c.PostStoryContext(context.Background(), mediaID, caption, time, mediaType)
+}
+
+// PostStoryContext is like PostStory but carries ctx to every request it makes.
+func (c *Casper) PostStoryContext(ctx context.Context, mediaID string, caption string, time int, mediaType string) ([]byte, error) {
+	res, err := c.call(ctx, "/bq/post_story", func(params map[string]string) {
+		params["camera_front_facing"] = "0"
+		params["media_id"] = mediaID
+		params["client_id"] = mediaID
+		params["type"] = mediaType
+		params["caption"] = caption
+		params["zipped"] = "0"
+		params["orientation"] = "0"
+		params["time"] = strconv.Itoa(time)
+		params["story_timestamp"] = params["timestamp"]
+	})
 	if err != nil {
 		return nil, err
 	}
-	return scdata, err
+	return res.Body, nil
 }
 
 // RetryPostStory retries to resend media to Snapchat users.
 // This method is sometimes used to quickly post a story to Snapchat.
 // TODO: Implement multipart requests instead of returning Options.
 func (c *Casper) RetryPostStory() (Options, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Options{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/retry_post_story",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Options{}, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return Options{}, err
-	}
-	retryPostStoryEndpoint := data.Endpoints[0] // upload endpoint data
-	headers := c.setSnapchatHeaders(data)       // headers
-	params := map[string]string{
-		"username":  retryPostStoryEndpoint.Params.Username,
-		"req_token": retryPostStoryEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(retryPostStoryEndpoint.Params.Timestamp, 10),
-	}
-	options := Options{
-		headers,
-		params,
-	}
-	return options, err
+	return c.RetryPostStoryContext(context.Background())
+}
+
+// RetryPostStoryContext is like RetryPostStory but carries ctx to every request it makes.
+func (c *Casper) RetryPostStoryContext(ctx context.Context) (Options, error) {
+	return c.options(ctx, "/bq/retry_post_story")
 }
 
 // DeleteStory deletes media from a Snapchat story.
 func (c *Casper) DeleteStory(id string) error {
-	err := c.checkToken()
-	if err != nil {
-		return err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/delete_story",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return err
-	}
-	data, err := c.endpointAuth(token)
+	return c.DeleteStoryContext(context.Background(), id)
+}
+
+// DeleteStoryContext is like DeleteStory but carries ctx to every request it makes.
+func (c *Casper) DeleteStoryContext(ctx context.Context, id string) error {
+	res, err := c.Call(ctx, "/bq/delete_story", map[string]string{
+		"story_id": id,
+	})
 	if err != nil {
 		return err
 	}
-	deleteStoryEndpoint := data.Endpoints[0] // upload endpoint data
-	endpoint := deleteStoryEndpoint.Endpoint // /bq/delete_story
-	headers := c.setSnapchatHeaders(data)    // headers
-	params := map[string]string{
-		"username":  deleteStoryEndpoint.Params.Username,
-		"story_id":  id,
-		"req_token": deleteStoryEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(deleteStoryEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	_, reqErr := s.performRequest("POST", endpoint, params, headers)
-	if reqErr != nil {
-		return err
-	}
-	if status != 204 {
-		return errors.New("snapchat: Something went wrong")
+	if res.StatusCode != 204 {
+		return SnapchatError{StatusCode: res.StatusCode}
 	}
 	return nil
 }
@@ -939,124 +838,68 @@ func (c *Casper) DeleteStory(id string) error {
 // DoublePost posts a snap to a users Snapchat story and to other Snapchat users.
 // TODO: Implement multipart requests instead of returning Options.
 func (c *Casper) DoublePost() (Options, error) {
-	err := c.checkToken()
-	if err != nil {
-		return Options{}, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/loq/double_post",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return Options{}, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return Options{}, err
-	}
-	doublePostEndpoint := data.Endpoints[0] // upload endpoint data
-	headers := c.setSnapchatHeaders(data)   // headers
-	params := map[string]string{
-		"username":  doublePostEndpoint.Params.Username,
-		"req_token": doublePostEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(doublePostEndpoint.Params.Timestamp, 10),
-	}
-	options := Options{
-		headers,
-		params,
-	}
-	return options, err
+	return c.DoublePostContext(context.Background())
+}
+
+// DoublePostContext is like DoublePost but carries ctx to every request it makes.
+func (c *Casper) DoublePostContext(ctx context.Context) (Options, error) {
+	return c.options(ctx, "/loq/double_post")
 }
 
 // UserExists checks if a username exists in Snapchat.
-func (c *Casper) UserExists(requestUsername string) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/user_exists",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	userExistsEndpoint := data.Endpoints[0] // upload endpoint data
-	endpoint := userExistsEndpoint.Endpoint // /bq/user_exists
-	headers := c.setSnapchatHeaders(data)   // headers
-	params := map[string]string{
-		"username":         userExistsEndpoint.Params.Username,
-		"req_token":        userExistsEndpoint.Params.ReqToken,
+func (c *Casper) UserExists(requestUsername string) (UserExists, error) {
+	return c.UserExistsContext(context.Background(), requestUsername)
+}
+
+// UserExistsContext is like UserExists but carries ctx to every request it makes.
+func (c *Casper) UserExistsContext(ctx context.Context, requestUsername string) (UserExists, error) {
+	res, err := c.Call(ctx, "/bq/user_exists", map[string]string{
 		"request_username": requestUsername,
-		"timestamp":        strconv.FormatInt(userExistsEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	})
 	if err != nil {
-		return nil, err
+		return UserExists{}, err
+	}
+	var existsData UserExists
+	if err := c.decode("/bq/user_exists", res.Body, &existsData); err != nil {
+		return UserExists{}, err
 	}
-	return scdata, err
+	existsData.Raw = res.Body
+	return existsData, nil
 }
 
 // FindFriends finds friends using a phone number from contacts.
-func (c *Casper) FindFriends(countryCode string, contacts map[string]string) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
+func (c *Casper) FindFriends(countryCode string, contacts map[string]string) (FindFriends, error) {
+	return c.FindFriendsContext(context.Background(), countryCode, contacts)
+}
+
+// FindFriendsContext is like FindFriends but carries ctx to every request it makes.
+func (c *Casper) FindFriendsContext(ctx context.Context, countryCode string, contacts map[string]string) (FindFriends, error) {
 	nums, err := json.Marshal(contacts)
 	if err != nil {
-		fmt.Println(err)
+		return FindFriends{}, err
 	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/find_friends",
-	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	findFriendsEndpoint := data.Endpoints[0] // upload endpoint data
-	endpoint := findFriendsEndpoint.Endpoint // /bq/find_friends
-	headers := c.setSnapchatHeaders(data)    // headers
-	params := map[string]string{
-		"username":    findFriendsEndpoint.Params.Username,
-		"req_token":   findFriendsEndpoint.Params.ReqToken,
+	res, err := c.Call(ctx, "/bq/find_friends", map[string]string{
 		"countryCode": countryCode,
 		"numbers":     string(nums),
-		"timestamp":   strconv.FormatInt(findFriendsEndpoint.Params.Timestamp, 10),
-	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	})
 	if err != nil {
-		return nil, err
+		return FindFriends{}, err
+	}
+	var friendsData FindFriends
+	if err := c.decode("/bq/find_friends", res.Body, &friendsData); err != nil {
+		return FindFriends{}, err
 	}
-	return scdata, err
+	friendsData.Raw = res.Body
+	return friendsData, nil
 }
 
 // Friend provides friend functions add, delete, block, unblock and display all in one method.
-func (c *Casper) Friend(friend string, action string, nickname string) ([]byte, error) {
-	err := c.checkToken()
-	if err != nil {
-		return nil, err
-	}
+func (c *Casper) Friend(friend string, action string, nickname string) (Friend, error) {
+	return c.FriendContext(context.Background(), friend, action, nickname)
+}
+
+// FriendContext is like Friend but carries ctx to every request it makes.
+func (c *Casper) FriendContext(ctx context.Context, friend string, action string, nickname string) (Friend, error) {
 	actions := []string{"add", "delete", "block", "unblock", "display"}
 	var match = false
 	for _, a := range actions {
@@ -1066,142 +909,236 @@ func (c *Casper) Friend(friend string, action string, nickname string) ([]byte,
 	}
 	if match == false {
 		msg := errors.New("\"" + action + "\"  is not a valid friend action")
-		return nil, Error{"casper: error", msg}
-	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/friend",
+		return Friend{}, Error{"casper: error", msg}
 	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return nil, err
-	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
-	}
-	friendEndpoint := data.Endpoints[0]   // upload endpoint data
-	endpoint := friendEndpoint.Endpoint   // /bq/friend
-	headers := c.setSnapchatHeaders(data) // headers
 	params := map[string]string{
-		"username":  friendEndpoint.Params.Username,
-		"req_token": friendEndpoint.Params.ReqToken,
-		"action":    action,
-		"friend":    friend,
-		"timestamp": strconv.FormatInt(friendEndpoint.Params.Timestamp, 10),
+		"action": action,
+		"friend": friend,
 	}
 	if action == "display" {
 		params["display"] = nickname
 	}
-	s := Snapchat{
-		CasperClient: c,
-	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	res, err := c.Call(ctx, "/bq/friend", params)
 	if err != nil {
-		return nil, err
+		return Friend{}, err
+	}
+	var friendData Friend
+	if err := c.decode("/bq/friend", res.Body, &friendData); err != nil {
+		return Friend{}, err
 	}
-	return scdata, err
+	friendData.Raw = res.Body
+	return friendData, nil
 }
 
 // BestFriends fetches best friends and scores on Snapchat.
-func (c *Casper) BestFriends(friends []string) ([]byte, error) {
-	err := c.checkToken()
+func (c *Casper) BestFriends(friends []string) (BestFriends, error) {
+	return c.BestFriendsContext(context.Background(), friends)
+}
+
+// BestFriendsContext is like BestFriends but carries ctx to every request it makes.
+func (c *Casper) BestFriendsContext(ctx context.Context, friends []string) (BestFriends, error) {
+	users, err := json.Marshal(friends)
 	if err != nil {
-		return nil, err
+		return BestFriends{}, err
 	}
-	users, err := json.Marshal(friends)
+	res, err := c.Call(ctx, "/bq/bests", map[string]string{
+		"friend_usernames": string(users),
+	})
 	if err != nil {
-		fmt.Println(err)
+		return BestFriends{}, err
 	}
-	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/bq/bests",
+	var bestsData BestFriends
+	if err := c.decode("/bq/bests", res.Body, &bestsData.Friends); err != nil {
+		return BestFriends{}, err
 	}
-	token, err := c.signToken(jwtform)
+	bestsData.Raw = res.Body
+	return bestsData, nil
+}
+
+// Logout logs the current use out of Snapchat.
+func (c *Casper) Logout() (bool, error) {
+	return c.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout but carries ctx to every request it makes.
+func (c *Casper) LogoutContext(ctx context.Context) (bool, error) {
+	username, _ := c.account()
+	res, err := c.Call(ctx, "/ph/logout", map[string]string{
+		"username": username,
+	})
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	data, err := c.endpointAuth(token)
-	if err != nil {
-		return nil, err
+	if res.StatusCode != 200 {
+		return false, SnapchatError{StatusCode: res.StatusCode}
 	}
-	bestsEndpoint := data.Endpoints[0]    // upload endpoint data
-	endpoint := bestsEndpoint.Endpoint    // /bq/bests
-	headers := c.setSnapchatHeaders(data) // headers
-	params := map[string]string{
-		"username":         bestsEndpoint.Params.Username,
-		"req_token":        bestsEndpoint.Params.ReqToken,
-		"friend_usernames": string(users),
-		"timestamp":        strconv.FormatInt(bestsEndpoint.Params.Timestamp, 10),
+	c.ExpireEndpointCache()
+	return true, nil
+}
+
+// Call performs a Casper signed request to any Snapchat endpoint and returns the raw Response.
+// The username, req_token and timestamp parameters are filled in from the Casper API,
+// extra holds any additional parameters the endpoint expects and may override them.
+func (c *Casper) Call(ctx context.Context, endpoint string, extra map[string]string) (*Response, error) {
+	return c.call(ctx, endpoint, extraParams(extra))
+}
+
+// extraParams returns a setParams func adding extra to the signed parameters.
+func extraParams(extra map[string]string) func(params map[string]string) {
+	return func(params map[string]string) {
+		for k, v := range extra {
+			params[k] = v
+		}
 	}
-	s := Snapchat{
-		CasperClient: c,
+}
+
+// call signs endpoint with the Casper API, lets setParams add to the signed parameters
+// and performs the Snapchat request.
+// A failed attempt drops the signature it used from the cache, so every retry signs endpoint again.
+func (c *Casper) call(ctx context.Context, endpoint string, setParams func(params map[string]string)) (*Response, error) {
+	return c.callSigned(ctx, endpoint, c.options, setParams)
+}
+
+// callSigned is like call but gets the signed Options for endpoint from sign.
+func (c *Casper) callSigned(ctx context.Context, endpoint string, sign func(ctx context.Context, endpoint string) (Options, error), setParams func(params map[string]string)) (*Response, error) {
+	var res *Response
+	err := c.relogin(ctx, func() error {
+		return c.retry(ctx, func() error {
+			opts, err := sign(ctx, endpoint)
+			if err != nil {
+				return err
+			}
+			if setParams != nil {
+				setParams(opts.Params)
+			}
+			s := Snapchat{
+				CasperClient: c,
+			}
+			res, err = s.do(ctx, "POST", opts.Endpoint, opts.Params, opts.Headers)
+			if err != nil {
+				username, _ := c.account()
+				c.endpoints.expire(username, endpoint)
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
-	scdata, err := s.performRequest("POST", endpoint, params, headers)
+	return res, nil
+}
+
+// open signs endpoint with the Casper API and sends it to Snapchat like Call, but returns the
+// *http.Response with its body left unread so media can be streamed. The caller must close the body.
+func (c *Casper) open(ctx context.Context, endpoint string, extra map[string]string) (*http.Response, error) {
+	var res *http.Response
+	err := c.relogin(ctx, func() error {
+		return c.retry(ctx, func() error {
+			opts, err := c.options(ctx, endpoint)
+			if err != nil {
+				return err
+			}
+			for k, v := range extra {
+				opts.Params[k] = v
+			}
+			s := Snapchat{
+				CasperClient: c,
+			}
+			req, err := s.newRequest(ctx, "POST", opts.Endpoint, opts.Params, opts.Headers)
+			if err != nil {
+				return err
+			}
+			res, err = s.open(req)
+			if err != nil {
+				username, _ := c.account()
+				c.endpoints.expire(username, endpoint)
+			}
+			return err
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
-	return scdata, err
+	return res, nil
 }
 
-// Logout logs the current use out of Snapchat.
-func (c *Casper) Logout() (bool, error) {
+// options fetches the signed headers and parameters needed to request endpoint from Snapchat.
+func (c *Casper) options(ctx context.Context, endpoint string) (Options, error) {
 	err := c.checkToken()
 	if err != nil {
-		return false, err
+		return Options{}, err
+	}
+	username, authToken := c.account()
+	scEndpoint, ok := c.endpoints.get(username, authToken, endpoint)
+	if !ok {
+		data, err := c.authorize(ctx, endpoint)
+		if err != nil {
+			return Options{}, err
+		}
+		scEndpoint, err = data.Endpoint(endpoint)
+		if err != nil {
+			return Options{}, err
+		}
 	}
+	return c.endpointOptions(endpoint, scEndpoint), nil
+}
+
+// authorize asks the Casper API to sign every endpoint in endpoints with a single endpointauth request
+// and caches the entries it returns. Several endpoints are sent as a comma separated endpoints claim,
+// which the Casper API does not document, so callers must not rely on getting an entry for each of them.
+func (c *Casper) authorize(ctx context.Context, endpoints ...string) (SnapchatRequestModel, error) {
+	username, authToken := c.account()
 	jwtform := map[string]string{
-		"username":   c.Username,
-		"auth_token": c.AuthToken,
-		"endpoint":   "/ph/logout",
+		"username":   username,
+		"auth_token": authToken,
 	}
-	token, err := c.signToken(jwtform)
-	if err != nil {
-		return false, err
+	if len(endpoints) == 1 {
+		jwtform["endpoint"] = endpoints[0]
+	} else {
+		jwtform["endpoints"] = strings.Join(endpoints, ",")
 	}
-	endpointData, err := c.endpointAuth(token)
+	token, err := c.signToken(jwtform)
 	if err != nil {
-		return false, err
-	}
-	logoutEndpoint := endpointData.Endpoints[0]   // logout endpoint data
-	endpoint := logoutEndpoint.Endpoint           // /loq/logout
-	headers := c.setSnapchatHeaders(endpointData) // update endpoint data
-	params := map[string]string{
-		"req_token": logoutEndpoint.Params.ReqToken,
-		"timestamp": strconv.FormatInt(logoutEndpoint.Params.Timestamp, 10),
-		"username":  c.Username,
-	}
-	s := Snapchat{
-		CasperClient: c,
+		return SnapchatRequestModel{}, err
 	}
-	_, err = s.performRequest("POST", endpoint, params, headers)
+	data, err := c.endpointAuth(ctx, token)
 	if err != nil {
-		return false, err
+		return SnapchatRequestModel{}, err
 	}
-	if status != 200 {
-		return false, errors.New("snapchat: Something went wrong")
+	c.endpoints.put(username, authToken, data)
+	return data, nil
+}
+
+// endpointOptions returns the Options for requesting endpoint from Snapchat with the signed scEndpoint.
+func (c *Casper) endpointOptions(endpoint string, scEndpoint SnapchatEndpoint) Options {
+	return Options{
+		Endpoint: endpoint,
+		Headers:  c.setSnapchatHeaders(scEndpoint),
+		Params: map[string]string{
+			"username":  scEndpoint.Params.Username,
+			"req_token": scEndpoint.Params.ReqToken,
+			"timestamp": strconv.FormatInt(scEndpoint.Params.Timestamp, 10),
+		},
 	}
-	return true, nil
 }
 
 // Proxy sets given string addr, as a proxy addr. Primarily for debugging purposes.
 func (c *Casper) Proxy(addr string) error {
 	proxyURL, err := url.Parse(addr)
 	if err != nil {
-		casperParseError.Reason = err
-		return casperParseError
+		return Error{Err: casperParseError, Reason: err}
 	}
 	if proxyURL.Scheme == "" {
 		return errors.New("invalid proxy url")
 	}
+	c.mu.Lock()
 	c.ProxyURL = proxyURL
+	c.mu.Unlock()
 	return nil
 }
 
 // login logs into Casper and returns a SnapchatRequestLoginModel.
-func (c *Casper) login(username string, password string) (SnapchatRequestLoginModel, error) {
+func (c *Casper) login(ctx context.Context, username string, password string) (SnapchatRequestLoginModel, error) {
 	jwtform := map[string]string{
 		"username": username,
 		"password": password,
@@ -1210,23 +1147,27 @@ func (c *Casper) login(username string, password string) (SnapchatRequestLoginMo
 	if err != nil {
 		return SnapchatRequestLoginModel{}, err
 	}
-	data, err := c.performRequest("POST", "/snapchat/ios/login", map[string]string{"jwt": token}, nil)
+	data, err := c.performRequest(ctx, "POST", "/snapchat/ios/login", map[string]string{"jwt": token}, nil)
 	if err != nil {
 		return SnapchatRequestLoginModel{}, err
 	}
 	var model SnapchatRequestLoginModel
-	json.Unmarshal(data, &model)
+	if err := c.decode("/snapchat/ios/login", data, &model); err != nil {
+		return SnapchatRequestLoginModel{}, err
+	}
 	return model, nil
 }
 
 // endpointAuth handles requests and responses to mutiple snapchat endpoints.
-func (c *Casper) endpointAuth(token string) (SnapchatRequestModel, error) {
-	data, err := c.performRequest("POST", "/snapchat/ios/endpointauth", map[string]string{"jwt": token}, nil)
+func (c *Casper) endpointAuth(ctx context.Context, token string) (SnapchatRequestModel, error) {
+	data, err := c.performRequest(ctx, "POST", "/snapchat/ios/endpointauth", map[string]string{"jwt": token}, nil)
 	if err != nil {
 		return SnapchatRequestModel{}, err
 	}
 	var scdata SnapchatRequestModel
-	json.Unmarshal(data, &scdata)
+	if err := c.decode("/snapchat/ios/endpointauth", data, &scdata); err != nil {
+		return SnapchatRequestModel{}, err
+	}
 	return scdata, nil
 }
 
@@ -1247,10 +1188,9 @@ func (c *Casper) signToken(params map[string]string) (string, error) {
 	return jwtString, nil
 }
 
-// setSnapchatHeaders converts the SnapchatRequestModel csrm to a map[string][string]
+// setSnapchatHeaders converts the headers of scEndpoint to a map[string][string]
 // much more easier to add to request headers.
-func (c *Casper) setSnapchatHeaders(csrm SnapchatRequestModel) map[string]string {
-	scEndpoint := csrm.Endpoints[0]
+func (c *Casper) setSnapchatHeaders(scEndpoint SnapchatEndpoint) map[string]string {
 	headers := map[string]string{
 		"Accept":                       scEndpoint.Headers.Accept,
 		"User-Agent":                   scEndpoint.Headers.UserAgent,
@@ -1261,25 +1201,17 @@ func (c *Casper) setSnapchatHeaders(csrm SnapchatRequestModel) map[string]string
 }
 
 // performRequest is a template that creates HTTP requests with proxy and debug support.
-func (c *Casper) performRequest(method string, endpoint string, params map[string]string, headers map[string]string) ([]byte, error) {
-	var tr *http.Transport
+func (c *Casper) performRequest(ctx context.Context, method string, endpoint string, params map[string]string, headers map[string]string) ([]byte, error) {
 	var casperForm url.Values
 	var req *http.Request
+	var err error
 
+	baseURL := c.casperURL()
 	if c.Debug == true {
-		fmt.Printf(method+"\t%s\n", CasperBaseURL+endpoint)
-	}
-
-	tr = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
-	}
-
-	if c.ProxyURL != nil {
-		tr.Proxy = http.ProxyURL(c.ProxyURL)
-		tr.TLSClientConfig.InsecureSkipVerify = true
+		fmt.Printf(method+"\t%s\n", baseURL+endpoint)
 	}
 
-	client := &http.Client{Transport: tr}
+	client := c.httpClient()
 
 	if params != nil {
 		casperForm = url.Values{}
@@ -1293,24 +1225,32 @@ func (c *Casper) performRequest(method string, endpoint string, params map[strin
 	}
 
 	if method == "GET" {
-		req, _ = http.NewRequest(method, CasperBaseURL+endpoint, nil)
+		req, err = http.NewRequest(method, baseURL+endpoint, nil)
 	} else {
-		req, _ = http.NewRequest(method, CasperBaseURL+endpoint, strings.NewReader(casperForm.Encode()))
+		req, err = http.NewRequest(method, baseURL+endpoint, strings.NewReader(casperForm.Encode()))
+	}
+	if err != nil {
+		return nil, Error{Err: casperHTTPError, Reason: err}
 	}
+	req = req.WithContext(ctx)
 
+	userAgent := "CasperGoAPIClient/1.1"
 	if c.ProjectName != "" {
-		c.ProjectName = c.ProjectName + " "
+		userAgent = "CasperGoAPIClient/" + c.ProjectName + " 1.1"
 	}
 
-	req.Header.Set("User-Agent", "CasperGoAPIClient/"+c.ProjectName+"1.1")
+	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("X-Casper-API-Key", c.APIKey)
 	req.Header.Set("Accept", "*/*")
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
+	if err := c.CasperLimiter.Wait(ctx); err != nil {
+		return nil, Error{Err: casperHTTPError, Reason: err}
+	}
+
 	res, err := client.Do(req)
 	if err != nil {
-		casperHTTPError.Reason = err
-		return nil, casperHTTPError
+		return nil, Error{Err: casperHTTPError, Reason: err}
 	}
 	defer res.Body.Close()
 
@@ -1322,8 +1262,10 @@ func (c *Casper) performRequest(method string, endpoint string, params map[strin
 	if res.StatusCode != 200 {
 		var model APIErrorResponseModel
 		json.Unmarshal(parsedData, &model)
-		casperHTTPError.Reason = errors.New(model.Message + "  (" + res.Status + ")")
-		return nil, casperHTTPError
+		model.Code = res.StatusCode
+		model.RetryAfter = parseRetryAfter(res.Header)
+		c.CasperLimiter.Delay(model.RetryAfter)
+		return nil, apiError(model)
 	}
 
 	if c.Debug == true {
@@ -1332,25 +1274,131 @@ func (c *Casper) performRequest(method string, endpoint string, params map[strin
 	return parsedData, nil
 }
 
+// casperURL returns the base URL of the Casper API used by c.
+func (c *Casper) casperURL() string {
+	if c.CasperURL != "" {
+		return strings.TrimSuffix(c.CasperURL, "/")
+	}
+	return CasperBaseURL
+}
+
+// snapchatURL returns the base URL of the Snapchat API used by c.
+func (c *Casper) snapchatURL() string {
+	if c.SnapchatURL != "" {
+		return strings.TrimSuffix(c.SnapchatURL, "/")
+	}
+	return SnapchatBaseURL
+}
+
+// httpClient returns the *http.Client used for requests made by c.
+// The default client is rebuilt only when ProxyURL changes, so connections are kept alive between calls.
+func (c *Casper) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil || c.clientProxy != c.ProxyURL {
+		c.client = newHTTPClient(c.ProxyURL)
+		c.clientProxy = c.ProxyURL
+	}
+	return c.client
+}
+
+// newHTTPClient creates a *http.Client with the default timeouts that sends its requests through proxyURL if it is not nil.
+func newHTTPClient(proxyURL *url.URL) *http.Client {
+	tr := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   DefaultDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: false},
+		TLSHandshakeTimeout:   DefaultTLSHandshakeTimeout,
+		ResponseHeaderTimeout: DefaultResponseHeaderTimeout,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+		MaxIdleConnsPerHost:   10,
+	}
+	if proxyURL != nil {
+		tr.Proxy = http.ProxyURL(proxyURL)
+		tr.TLSClientConfig.InsecureSkipVerify = true
+	}
+	return &http.Client{Transport: tr}
+}
+
 // parseBody is a helper function that parses the *http.Response body res to bytes.
 func parseBody(res *http.Response) ([]byte, error) {
 	parsedBody, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		casperParseError.Reason = err
-		return nil, casperParseError
+		return nil, Error{Err: casperParseError, Reason: err}
 	}
 	return parsedBody, nil
 }
 
+// newMediaID generates a Snapchat media ID, the uppercase username followed by a random UUID.
+func (c *Casper) newMediaID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4.
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant.
+	uuid := fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	username, _ := c.account()
+	return strings.ToUpper(username) + "~" + uuid, nil
+}
+
+// multipartBody streams params and the file read from r as the form field name of a multipart body.
+// It returns the body along with its Content-Type. Closing the body stops the stream.
+func multipartBody(params map[string]string, name string, r io.Reader) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		for k, v := range params {
+			if err := mw.WriteField(k, v); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		part, err := mw.CreateFormFile(name, name)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(part, r); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(mw.Close())
+	}()
+	return pr, mw.FormDataContentType()
+}
+
+// captchaIDFromHeader extracts the captcha ID from the Content-Disposition header h
+// of a /bq/get_captcha response.
+func captchaIDFromHeader(h http.Header) string {
+	disposition := h.Get("Content-Disposition")
+	if len(disposition) < 20 {
+		return ""
+	}
+	return disposition[20:]
+}
+
 // checkToken checks if a Snapchat authtoken exists.
 func (c *Casper) checkToken() error {
-	if c.AuthToken == "" || c.Username == "" {
-		casperAuthError.Reason = errors.New("auth token or username does not exist")
-		return casperAuthError
+	username, authToken := c.account()
+	if authToken == "" || username == "" {
+		return Error{Err: casperAuthError, Reason: errors.New("auth token or username does not exist")}
 	}
 	return nil
 }
 
+// account returns the username and auth token c is logged in with.
+func (c *Casper) account() (string, string) {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
+	return c.Username, c.AuthToken
+}
+
 // Casper Structs
 
 // SnapchatRequestLoginModel is a struct containing the endpoint headers parameters specifically for login.
@@ -1392,43 +1440,63 @@ type SnapchatRequestLoginModel struct {
 
 // SnapchatRequestModel is a generic struct containing the endpoint headers and parameters for any Snapchat endpoint.
 type SnapchatRequestModel struct {
-	Code      int `json:"code"`
-	Endpoints []struct {
-		CacheMillis int    `json:"cache_millis"`
-		Endpoint    string `json:"endpoint"`
-		Headers     struct {
-			Accept                   string `json:"Accept"`
-			UserAgent                string `json:"User-Agent"`
-			XSnapchatClientAuthToken string `json:"X-Snapchat-Client-Auth-Token"`
-			XSnapchatUUID            string `json:"X-Snapchat-UUID"`
-		} `json:"headers"`
-		Params struct {
-			Username  string `json:"username"`
-			ReqToken  string `json:"req_token"`
-			Timestamp int64  `json:"timestamp"`
-		} `json:"params"`
-	} `json:"endpoints"`
-	Settings struct {
+	Code      int                `json:"code"`
+	Endpoints []SnapchatEndpoint `json:"endpoints"`
+	Settings  struct {
 		ForceExpireCached bool `json:"force_expire_cached"`
 	} `json:"settings"`
 }
 
+// SnapchatEndpoint contains the headers and parameters signed by the Casper API for a single Snapchat endpoint.
+type SnapchatEndpoint struct {
+	CacheMillis int    `json:"cache_millis"`
+	Endpoint    string `json:"endpoint"`
+	Headers     struct {
+		Accept                   string `json:"Accept"`
+		UserAgent                string `json:"User-Agent"`
+		XSnapchatClientAuthToken string `json:"X-Snapchat-Client-Auth-Token"`
+		XSnapchatUUID            string `json:"X-Snapchat-UUID"`
+	} `json:"headers"`
+	Params struct {
+		Username  string `json:"username"`
+		ReqToken  string `json:"req_token"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"params"`
+}
+
+// Endpoint returns the entry signed for endpoint, matched by its path rather than by its position in Endpoints.
+func (m SnapchatRequestModel) Endpoint(endpoint string) (SnapchatEndpoint, error) {
+	for _, e := range m.Endpoints {
+		if endpointPath(e.Endpoint) == endpointPath(endpoint) {
+			return e, nil
+		}
+	}
+	return SnapchatEndpoint{}, Error{Err: casperEndpointError, Reason: fmt.Errorf("no endpoint %s in endpointauth response", endpoint)}
+}
+
+// endpointPath returns the path of endpoint, which the Casper API may send as a full URL.
+func endpointPath(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return endpoint
+}
+
 // APIErrorResponseModel is a struct containing just a HTTP status and a message specifying an error occured.
 type APIErrorResponseModel struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code       int           `json:"code"`
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"`
 }
 
 // GetAttestation fetches a valid Google attestation using the Casper API.
 // [DEPRECATED]
 func (c *Casper) GetAttestation(username, password, timestamp string) (string, error) {
-	casperDeprecatedError.Reason = errors.New("func (*Casper) GetAttestation is deprecated and will not work.\nPlease refrain from using this method")
-	return "", casperDeprecatedError
+	return "", Error{Err: casperDeprecatedError, Reason: errors.New("func (*Casper) GetAttestation is deprecated and will not work.\nPlease refrain from using this method")}
 }
 
 // GetClientAuthToken fetches a generated client auth token using the Casper API.
 // [DEPRECATED]
 func (c *Casper) GetClientAuthToken(username, password, timestamp string) (string, error) {
-	casperDeprecatedError.Reason = errors.New("func (*Casper) GetClientAuthToken is deprecated and will not work.\nPlease refrain from using this method")
-	return "", casperDeprecatedError
+	return "", Error{Err: casperDeprecatedError, Reason: errors.New("func (*Casper) GetClientAuthToken is deprecated and will not work.\nPlease refrain from using this method")}
 }
@@ -1,11 +1,17 @@
 package casper
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -24,6 +30,46 @@ var (
 	testHTTPhandler http.Handler
 )
 
+// testClaims decodes the claims of the JWT posted to the fake Casper API, without verifying it.
+func testClaims(req *http.Request) map[string]interface{} {
+	var claims map[string]interface{}
+	segments := strings.Split(req.FormValue("jwt"), ".")
+	if len(segments) == 3 {
+		payload, _ := base64.RawURLEncoding.DecodeString(segments[1])
+		json.Unmarshal(payload, &claims)
+	}
+	return claims
+}
+
+// writeTestEndpointAuth writes a Casper endpointauth response signing the endpoint in claims with reqToken.
+func writeTestEndpointAuth(rw http.ResponseWriter, claims map[string]interface{}, reqToken string) {
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"cache_millis": 0, "endpoint": "%s", "headers": {"Accept": "*/*", "User-Agent": "Snapchat/9.26.0.1 (iPhone6,1; iOS 9.0; gzip)", "X-Snapchat-Client-Auth-Token": "test_client_auth_token", "X-Snapchat-UUID": "test_uuid"}, "params": {"username": "%s", "req_token": "%s", "timestamp": 1457484764}}], "settings": {"force_expire_cached": false}}`, claims["endpoint"], claims["username"], reqToken)
+}
+
+// newTestCasper returns a Casper client pointed at a fake Casper API, which signs every endpoint it is asked for,
+// and a fake Snapchat API served by snapchat. The returned func closes both servers.
+func newTestCasper(snapchat http.HandlerFunc) (*Casper, func()) {
+	casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		claims := testClaims(req)
+		writeTestEndpointAuth(rw, claims, "test_req_token")
+	}))
+	snapchatServer := httptest.NewServer(snapchat)
+
+	testCasperClient := &Casper{
+		APIKey:      testCasperKeys.TestAPIKey,
+		APISecret:   testCasperKeys.TestAPISecret,
+		Username:    "test_api_user",
+		AuthToken:   "test_auth_token",
+		CasperURL:   casperServer.URL,
+		SnapchatURL: snapchatServer.URL,
+	}
+	return testCasperClient, func() {
+		casperServer.Close()
+		snapchatServer.Close()
+	}
+}
+
 // Test SignToken.
 func TestSignToken(t *testing.T) {
 	var testCasperClient = &Casper{
@@ -149,3 +195,299 @@ func TestInvalidProxy(t *testing.T) {
 		}
 	}
 }
+
+// Test CaptchaIDFromHeader.
+func TestCaptchaIDFromHeader(t *testing.T) {
+	var paramTests = []struct {
+		disposition string
+		expectation string
+	}{
+		{"attachment;filename=test_user~1457484764.zip", "test_user~1457484764.zip"},
+		{"attachment;", ""},
+		{"", ""},
+	}
+
+	for _, test := range paramTests {
+		header := http.Header{}
+		header.Set("Content-Disposition", test.disposition)
+		result := captchaIDFromHeader(header)
+		if result != test.expectation {
+			t.Errorf("captchaIDFromHeader(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.disposition, test.expectation, result)
+		}
+	}
+}
+
+// Test ContextCanceled.
+func TestContextCanceled(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, `{}`)
+	})
+	defer closeServers()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := testCasperClient.UpdatesContext(ctx)
+	if err == nil {
+		t.Errorf("UpdatesContext(%s) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "canceled context", "error", "<nil>")
+	}
+}
+
+// Test HTTPClient.
+func TestHTTPClient(t *testing.T) {
+	var testCasperClient = &Casper{
+		APIKey:    testCasperKeys.TestAPIKey,
+		APISecret: testCasperKeys.TestAPISecret,
+	}
+
+	client := testCasperClient.httpClient()
+	if testCasperClient.httpClient() != client {
+		t.Errorf("httpClient() failed test. The default client was not reused.")
+	}
+
+	if err := testCasperClient.Proxy("http://192.168.2.3:8080"); err != nil {
+		t.Fatal(err)
+	}
+	if testCasperClient.httpClient() == client {
+		t.Errorf("httpClient() failed test. The default client was not rebuilt after setting a proxy.")
+	}
+
+	testCasperClient.HTTPClient = &http.Client{}
+	if testCasperClient.httpClient() != testCasperClient.HTTPClient {
+		t.Errorf("httpClient() failed test. The HTTPClient field was not used.")
+	}
+}
+
+// Test Call.
+func TestCall(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		expected := map[string]string{
+			"username":  "test_api_user",
+			"req_token": "test_req_token",
+			"timestamp": "1457484764",
+			"action":    "list",
+		}
+		for k, v := range expected {
+			if req.FormValue(k) != v {
+				t.Errorf("Call form value %q failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", k, v, req.FormValue(k))
+			}
+		}
+		if req.Header.Get("X-Snapchat-UUID") != "test_uuid" {
+			t.Errorf("Call header %q failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "X-Snapchat-UUID", "test_uuid", req.Header.Get("X-Snapchat-UUID"))
+		}
+		fmt.Fprintf(rw, `{"path": "%s"}`, req.URL.Path)
+	})
+	defer closeServers()
+
+	res, err := testCasperClient.Call(context.Background(), "/bq/suggest_friend", map[string]string{"action": "list"})
+	if err != nil {
+		t.Fatalf("Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/bq/suggest_friend", "<nil>", err)
+	}
+	expectation := `{"path": "/bq/suggest_friend"}`
+	if res.StatusCode != 200 || string(res.Body) != expectation {
+		t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/bq/suggest_friend", expectation, res.Body)
+	}
+}
+
+// Test ConcurrentClients.
+func TestConcurrentClients(t *testing.T) {
+	var paramTests = []struct {
+		status int
+	}{
+		{200},
+		{204},
+	}
+
+	var wg sync.WaitGroup
+	for _, test := range paramTests {
+		status := test.status
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(status)
+		})
+		defer closeServers()
+		testCasperClient.ProjectName = "test_project"
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := testCasperClient.Call(context.Background(), "/ph/logout", nil)
+				if err != nil {
+					t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/ph/logout", "<nil>", err)
+					return
+				}
+				if res.StatusCode != status {
+					t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", "/ph/logout", status, res.StatusCode)
+				}
+			}()
+		}
+		wg.Wait()
+		if testCasperClient.ProjectName != "test_project" {
+			t.Errorf("ProjectName failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "test_project", testCasperClient.ProjectName)
+		}
+	}
+}
+
+// Test Upload.
+func TestUpload(t *testing.T) {
+	media := "test_media_data"
+	var uploadedID string
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Upload() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "multipart body", err)
+		}
+		expected := map[string]string{
+			"username":  "test_api_user",
+			"req_token": "test_req_token",
+			"timestamp": "1457484764",
+			"type":      "0",
+		}
+		for k, v := range expected {
+			if req.FormValue(k) != v {
+				t.Errorf("Upload() form value %q failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", k, v, req.FormValue(k))
+			}
+		}
+		file, _, err := req.FormFile("data")
+		if err != nil {
+			t.Fatalf("Upload() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "data file", err)
+		}
+		data, _ := ioutil.ReadAll(file)
+		if string(data) != media {
+			t.Errorf("Upload() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", media, data)
+		}
+		uploadedID = req.FormValue("media_id")
+	})
+	defer closeServers()
+
+	mediaID, err := testCasperClient.Upload(strings.NewReader(media), MediaImage)
+	if err != nil {
+		t.Fatalf("Upload() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "<nil>", err)
+	}
+	if !strings.HasPrefix(mediaID, "TEST_API_USER~") || mediaID != uploadedID {
+		t.Errorf("Upload() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", uploadedID, mediaID)
+	}
+}
+
+// Test Stories.
+func TestStories(t *testing.T) {
+	var paramTests = []struct {
+		endpoints   string
+		expectation error
+	}{
+		{`[{"endpoint": "/loq/all_updates", "params": {"req_token": "wrong_req_token"}}, {"endpoint": "/bq/stories", "params": {"req_token": "test_req_token"}}]`, nil},
+		{`[{"endpoint": "https://app.snapchat.com/bq/stories", "params": {"req_token": "test_req_token"}}]`, nil},
+		{`[]`, ErrEndpoint},
+		{`[{"endpoint": "/loq/all_updates", "params": {"req_token": "wrong_req_token"}}]`, ErrEndpoint},
+	}
+
+	for _, test := range paramTests {
+		endpoints := test.endpoints
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/bq/stories" || req.FormValue("req_token") != "test_req_token" {
+				t.Errorf("Stories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", endpoints, "/bq/stories test_req_token", req.URL.Path+" "+req.FormValue("req_token"))
+			}
+			rw.Write([]byte(`{"my_stories": [{"story": {"id": "test_story_id"}}]}`))
+		})
+		casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(rw, `{"code": 200, "endpoints": %s}`, endpoints)
+		}))
+		testCasperClient.CasperURL = casperServer.URL
+
+		stories, err := testCasperClient.Stories()
+		if !errors.Is(err, test.expectation) {
+			t.Errorf("Stories(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", endpoints, test.expectation, err)
+		}
+		if err == nil && (len(stories.MyStories) != 1 || stories.MyStories[0].Story.ID != "test_story_id") {
+			t.Errorf("Stories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", endpoints, "test_story_id", stories.MyStories)
+		}
+		casperServer.Close()
+		closeServers()
+	}
+}
+
+// Test typed responses.
+func TestTypedResponses(t *testing.T) {
+	responses := map[string]string{
+		"/bq/user_exists":      `{"exists": true, "logged": true}`,
+		"/bq/snaptag_download": `{"imageData": "test_image_data", "qrPath": "test_qr_path"}`,
+		"/bq/bests":            `{"test_friend": {"best_friends": ["test_best_friend"], "score": 42}}`,
+		"/loq/send":            `{"snap_response": {"success": true, "snaps": {"test_friend": {"id": "test_snap_id"}}}}`,
+	}
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(responses[req.URL.Path]))
+	})
+	defer closeServers()
+
+	var paramTests = []struct {
+		endpoint    string
+		call        func() (interface{}, []byte, error)
+		expectation string
+	}{
+		{"/bq/user_exists", func() (interface{}, []byte, error) {
+			res, err := testCasperClient.UserExists("test_friend")
+			return res.Exists, res.Raw, err
+		}, "true"},
+		{"/bq/snaptag_download", func() (interface{}, []byte, error) {
+			res, err := testCasperClient.DownloadSnapTag("test_user_id", "SVG")
+			return res.Imagedata, res.Raw, err
+		}, "test_image_data"},
+		{"/bq/bests", func() (interface{}, []byte, error) {
+			res, err := testCasperClient.BestFriends([]string{"test_friend"})
+			return res.Friends["test_friend"].Score, res.Raw, err
+		}, "42"},
+		{"/loq/send", func() (interface{}, []byte, error) {
+			res, err := testCasperClient.Send("TEST_API_USER~media", []string{"test_friend"}, 10)
+			return res.SnapResponse.Snaps["test_friend"].ID, res.Raw, err
+		}, "test_snap_id"},
+	}
+
+	for _, test := range paramTests {
+		result, raw, err := test.call()
+		if err != nil {
+			t.Fatalf("%s failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.endpoint, "<nil>", err)
+		}
+		if fmt.Sprint(result) != test.expectation {
+			t.Errorf("%s failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", test.endpoint, test.expectation, result)
+		}
+		if string(raw) != responses[test.endpoint] {
+			t.Errorf("%s failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.endpoint, responses[test.endpoint], raw)
+		}
+	}
+}
+
+// Test Updates conversation state and friendmoji maps.
+func TestUpdatesMaps(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{
+			"updates_response": {"friendmoji_dict": {"on_fire": {"default_val": "fire"}, "brand_new_kind": {"default_val": "new"}}},
+			"conversations_response": [{"conversation_state": {
+				"user_sequences": {"test_api_user": 3, "test_friend": 5},
+				"user_chat_releases": {"test_api_user": {"test_friend": 1457484764}},
+				"user_snap_releases": {"test_friend": {"test_api_user": 1457484765}}
+			}}]
+		}`))
+	})
+	defer closeServers()
+
+	updates, err := testCasperClient.Updates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := updates.ConversationsResponse[0].ConversationState
+	var paramTests = []struct {
+		name        string
+		result      interface{}
+		expectation string
+	}{
+		{"friendmoji_dict", updates.UpdatesResponse.FriendmojiDict["brand_new_kind"].DefaultVal, "new"},
+		{"user_sequences", state.UserSequences["test_friend"], "5"},
+		{"user_chat_releases", state.UserChatReleases["test_api_user"]["test_friend"], "1457484764"},
+		{"user_snap_releases", state.UserSnapReleases["test_friend"]["test_api_user"], "1457484765"},
+	}
+
+	for _, test := range paramTests {
+		if fmt.Sprint(test.result) != test.expectation {
+			t.Errorf("Updates() %s failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", test.name, test.expectation, test.result)
+		}
+	}
+}
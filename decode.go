@@ -0,0 +1,123 @@
+package casper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxSnippet is how much of a response body a DecodeError keeps.
+const maxSnippet = 256
+
+// emptyBodyEndpoints lists the endpoints Snapchat answers with an empty body when they succeed.
+var emptyBodyEndpoints = map[string]bool{
+	"/loq/send": true,
+}
+
+// DecodeError reports a response body that could not be decoded into its model.
+// It matches ErrParse with errors.Is.
+type DecodeError struct {
+	Endpoint string
+	Snippet  string
+	Err      error
+}
+
+// Error returns the endpoint, the reason and the start of the body that failed to decode.
+func (e DecodeError) Error() string {
+	return fmt.Sprintf("casper: decoding response of %s: %v: %q", e.Endpoint, e.Err, e.Snippet)
+}
+
+// Unwrap returns the error of the JSON decoder.
+func (e DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrParse.
+func (e DecodeError) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.Err == casperParseError
+}
+
+// decode decodes the response body of endpoint into v.
+// An empty body leaves v untouched for the endpoints in emptyBodyEndpoints and fails for any other.
+// With c.Strict, a body holding a top-level field v does not know fails as well.
+func (c *Casper) decode(endpoint string, body []byte, v interface{}) error {
+	if len(bytes.TrimSpace(body)) == 0 && emptyBodyEndpoints[endpoint] {
+		return nil
+	}
+	err := json.Unmarshal(body, v)
+	if err == nil && c.Strict {
+		err = checkShape(body, v)
+	}
+	if err == nil && c.Drift != nil {
+		c.Drift.record(endpoint, body, v)
+	}
+	if err != nil {
+		snippet := string(body)
+		if len(snippet) > maxSnippet {
+			snippet = snippet[:maxSnippet] + "..."
+		}
+		return DecodeError{
+			Endpoint: endpoint,
+			Snippet:  snippet,
+			Err:      err,
+		}
+	}
+	return nil
+}
+
+// checkShape checks that body is a JSON object when v points to a struct or a map,
+// and that every top-level field of body is known to the struct.
+func checkShape(body []byte, v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return fmt.Errorf("expected a JSON object")
+	}
+	if t.Kind() == reflect.Map {
+		return nil
+	}
+	known := jsonFields(t)
+	for name := range fields {
+		if _, ok := known[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown field %q", name)
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the fields of struct type t by their lower cased JSON names,
+// including those of embedded structs, as encoding/json matches them case insensitively.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for embeddedName, embedded := range jsonFields(f.Type) {
+				fields[embeddedName] = embedded
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f
+	}
+	return fields
+}
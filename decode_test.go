@@ -0,0 +1,77 @@
+package casper
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Test Decode.
+func TestDecode(t *testing.T) {
+	var paramTests = []struct {
+		strict      bool
+		endpoint    string
+		body        string
+		expectation error
+	}{
+		{false, "/bq/user_exists", `{"exists": true, "unknown_field": 1}`, nil},
+		{false, "/bq/user_exists", ``, ErrParse},
+		{false, "/loq/send", ``, nil},
+		{false, "/bq/user_exists", `<html><body>503 Service Unavailable</body></html>`, ErrParse},
+		{false, "/bq/user_exists", `[true]`, ErrParse},
+		{false, "/bq/user_exists", `{"exists": "yes"}`, ErrParse},
+		{true, "/bq/user_exists", `{"exists": true, "Logged": true}`, nil},
+		{true, "/bq/user_exists", `{"exists": true, "unknown_field": 1}`, ErrParse},
+		{true, "/bq/user_exists", `null`, ErrParse},
+		{true, "/bq/user_exists", ``, ErrParse},
+		{true, "/loq/send", ``, nil},
+	}
+
+	for _, test := range paramTests {
+		testCasperClient := &Casper{
+			Strict: test.strict,
+		}
+		var result UserExists
+		err := testCasperClient.decode(test.endpoint, []byte(test.body), &result)
+		if !errors.Is(err, test.expectation) {
+			t.Errorf("decode(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", test.body, test.expectation, err)
+		}
+		var decodeErr DecodeError
+		if err != nil && (!errors.As(err, &decodeErr) || decodeErr.Endpoint != test.endpoint) {
+			t.Errorf("decode(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", test.body, "DecodeError of "+test.endpoint, err)
+		}
+	}
+}
+
+// Test decode failures surfaced by Updates and Login.
+func TestDecodeErrors(t *testing.T) {
+	var page atomic.Value
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/loq/login" {
+			rw.Write([]byte(`{"updates_response": {}}`))
+			return
+		}
+		rw.Write([]byte(page.Load().(string)))
+	})
+	defer closeServers()
+
+	page.Store("")
+	_, err := testCasperClient.Updates()
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("Updates() failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", ErrParse, err)
+	}
+
+	page.Store("<html>" + strings.Repeat("x", 1000) + "</html>")
+	_, err = testCasperClient.Updates()
+	var decodeErr DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Endpoint != "/loq/all_updates" || len(decodeErr.Snippet) > maxSnippet+3 {
+		t.Errorf("Updates() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", "DecodeError of /loq/all_updates", err)
+	}
+
+	_, err = testCasperClient.Login("test_api_user", "test_api_password")
+	if !errors.Is(err, ErrAuth) || testCasperClient.AuthToken != "test_auth_token" {
+		t.Errorf("Login(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "test_api_user", ErrAuth, err)
+	}
+}
@@ -0,0 +1,229 @@
+package casper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxDriftDepth bounds how deep a model is walked when comparing it to a response.
+const maxDriftDepth = 32
+
+// DriftReport collects the differences between Snapchat responses and the models they are decoded into,
+// so the models can be kept current. Set it as Casper.Drift to compare every decoded response.
+// A DriftReport is safe for concurrent use and can be shared by several clients.
+//
+// Paths join JSON keys with dots, mark the elements of arrays with [] and the values of maps with *.
+type DriftReport struct {
+	mu     sync.Mutex
+	models map[driftKey]*modelDrift
+}
+
+// Drift holds the differences found for the model of one endpoint.
+// Unknown lists the JSON keys responses held that the model does not know, with how often they were seen.
+// Missing lists the fields of the model no response has held so far.
+type Drift struct {
+	Endpoint  string
+	Model     string
+	Responses int
+	Unknown   map[string]int
+	Missing   []string
+}
+
+// driftKey identifies the model of an endpoint.
+type driftKey struct {
+	endpoint string
+	model    string
+}
+
+// modelDrift accumulates what responses of one endpoint held compared to its model.
+type modelDrift struct {
+	t         reflect.Type
+	responses int
+	unknown   map[string]int
+	seen      map[string]bool
+}
+
+// NewDriftReport returns an empty DriftReport.
+func NewDriftReport() *DriftReport {
+	return &DriftReport{
+		models: make(map[driftKey]*modelDrift),
+	}
+}
+
+// Drifts returns the differences found so far for every endpoint, sorted by endpoint and model.
+func (r *DriftReport) Drifts() []Drift {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var drifts []Drift
+	for key, m := range r.models {
+		unknown := make(map[string]int, len(m.unknown))
+		for path, n := range m.unknown {
+			unknown[path] = n
+		}
+		var missing []string
+		for _, path := range fieldPaths(m.t, "", 0) {
+			if !m.seen[path] {
+				missing = append(missing, path)
+			}
+		}
+		sort.Strings(missing)
+		drifts = append(drifts, Drift{
+			Endpoint:  key.endpoint,
+			Model:     key.model,
+			Responses: m.responses,
+			Unknown:   unknown,
+			Missing:   missing,
+		})
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Endpoint != drifts[j].Endpoint {
+			return drifts[i].Endpoint < drifts[j].Endpoint
+		}
+		return drifts[i].Model < drifts[j].Model
+	})
+	return drifts
+}
+
+// String returns the report as text, one block per endpoint.
+func (r *DriftReport) String() string {
+	var b strings.Builder
+	for _, d := range r.Drifts() {
+		fmt.Fprintf(&b, "%s (%s, %d responses)\n", d.Endpoint, d.Model, d.Responses)
+		var unknown []string
+		for path := range d.Unknown {
+			unknown = append(unknown, path)
+		}
+		sort.Strings(unknown)
+		for _, path := range unknown {
+			fmt.Fprintf(&b, "\t+ %s (%d)\n", path, d.Unknown[path])
+		}
+		for _, path := range d.Missing {
+			fmt.Fprintf(&b, "\t- %s\n", path)
+		}
+	}
+	return b.String()
+}
+
+// record compares the response body of endpoint to v, the model it was decoded into.
+func (r *DriftReport) record(endpoint string, body []byte, v interface{}) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	model := t.Name()
+	if model == "" {
+		model = t.Kind().String()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := driftKey{endpoint, model}
+	m, ok := r.models[key]
+	if !ok {
+		m = &modelDrift{
+			t:       t,
+			unknown: make(map[string]int),
+			seen:    make(map[string]bool),
+		}
+		if r.models == nil {
+			r.models = make(map[driftKey]*modelDrift)
+		}
+		r.models[key] = m
+	}
+	m.responses++
+	m.walk(data, t, "", 0)
+}
+
+// walk records the keys of data found and not found in t, at path.
+func (m *modelDrift) walk(data interface{}, t reflect.Type, path string, depth int) {
+	if depth > maxDriftDepth {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for name, value := range object {
+			fieldPath := joinPath(path, name)
+			f, ok := fields[strings.ToLower(name)]
+			if !ok {
+				m.unknown[fieldPath]++
+				continue
+			}
+			fieldPath = joinPath(path, jsonName(f))
+			m.seen[fieldPath] = true
+			m.walk(value, f.Type, fieldPath, depth+1)
+		}
+	case reflect.Map:
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, value := range object {
+			m.walk(value, t.Elem(), joinPath(path, "*"), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := data.([]interface{})
+		if !ok {
+			return
+		}
+		for _, value := range array {
+			m.walk(value, t.Elem(), path+"[]", depth+1)
+		}
+	}
+}
+
+// fieldPaths returns the paths of every field of t and of the structs it holds.
+func fieldPaths(t reflect.Type, path string, depth int) []string {
+	if depth > maxDriftDepth {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		var paths []string
+		for _, f := range jsonFields(t) {
+			fieldPath := joinPath(path, jsonName(f))
+			paths = append(paths, fieldPath)
+			paths = append(paths, fieldPaths(f.Type, fieldPath, depth+1)...)
+		}
+		return paths
+	case reflect.Map:
+		return fieldPaths(t.Elem(), joinPath(path, "*"), depth+1)
+	case reflect.Slice, reflect.Array:
+		return fieldPaths(t.Elem(), path+"[]", depth+1)
+	}
+	return nil
+}
+
+// jsonName returns the JSON name of struct field f.
+func jsonName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// joinPath appends key to path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,56 @@
+package casper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test DriftReport.
+func TestDriftReport(t *testing.T) {
+	report := NewDriftReport()
+	testCasperClient := &Casper{
+		Drift: report,
+	}
+
+	var paramTests = []struct {
+		body string
+	}{
+		{`{"exists": true, "new_field": {"nested": 1}}`},
+		{`{"exists": false, "new_field": 2}`},
+	}
+
+	for _, test := range paramTests {
+		var result UserExists
+		if err := testCasperClient.decode("/bq/user_exists", []byte(test.body), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var findFriends FindFriends
+	if err := testCasperClient.decode("/bq/find_friends", []byte(`{"results": [{"name": "test_friend", "is_new": true}]}`), &findFriends); err != nil {
+		t.Fatal(err)
+	}
+
+	expectation := []Drift{
+		{
+			Endpoint:  "/bq/find_friends",
+			Model:     "FindFriends",
+			Responses: 1,
+			Unknown:   map[string]int{"results[].is_new": 1},
+			Missing:   []string{"logged", "results[].display", "results[].type"},
+		},
+		{
+			Endpoint:  "/bq/user_exists",
+			Model:     "UserExists",
+			Responses: 2,
+			Unknown:   map[string]int{"new_field": 2},
+			Missing:   []string{"logged"},
+		},
+	}
+	if result := report.Drifts(); !reflect.DeepEqual(result, expectation) {
+		t.Errorf("DriftReport.Drifts() failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", expectation, result)
+	}
+	if result := report.String(); !strings.Contains(result, "\t+ new_field (2)\n") || !strings.Contains(result, "\t- logged\n") {
+		t.Errorf("DriftReport.String() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "+ new_field (2), - logged", result)
+	}
+}
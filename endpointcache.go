@@ -0,0 +1,86 @@
+package casper
+
+import (
+	"sync"
+	"time"
+)
+
+// endpointCache holds endpointauth entries until the cache_millis the Casper API sent with them runs out,
+// so repeated calls to the same endpoint do not cost a Casper request each.
+// The zero value is an empty cache ready to use.
+type endpointCache struct {
+	mu      sync.Mutex
+	entries map[endpointKey]cachedEndpoint
+}
+
+// endpointKey identifies the account and endpoint an entry was signed for.
+type endpointKey struct {
+	username string
+	endpoint string
+}
+
+// cachedEndpoint is a signed endpoint, the auth token it was signed with and when it stops being valid.
+type cachedEndpoint struct {
+	endpoint  SnapchatEndpoint
+	authToken string
+	expires   time.Time
+}
+
+// get returns the entry cached for username and endpoint if it is still valid and signed for authToken.
+func (ec *endpointCache) get(username, authToken, endpoint string) (SnapchatEndpoint, bool) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	key := endpointKey{username, endpoint}
+	cached, ok := ec.entries[key]
+	if !ok {
+		return SnapchatEndpoint{}, false
+	}
+	if cached.authToken != authToken || !time.Now().Before(cached.expires) {
+		delete(ec.entries, key)
+		return SnapchatEndpoint{}, false
+	}
+	return cached.endpoint, true
+}
+
+// put caches every entry of data for username. Entries without cache_millis are not cached.
+// When data sets force_expire_cached, everything cached for username is dropped and nothing is cached.
+func (ec *endpointCache) put(username, authToken string, data SnapchatRequestModel) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	if data.Settings.ForceExpireCached {
+		for key := range ec.entries {
+			if key.username == username {
+				delete(ec.entries, key)
+			}
+		}
+		return
+	}
+	now := time.Now()
+	for _, e := range data.Endpoints {
+		if e.CacheMillis <= 0 {
+			continue
+		}
+		if ec.entries == nil {
+			ec.entries = make(map[endpointKey]cachedEndpoint)
+		}
+		ec.entries[endpointKey{username, endpointPath(e.Endpoint)}] = cachedEndpoint{
+			endpoint:  e,
+			authToken: authToken,
+			expires:   now.Add(time.Duration(e.CacheMillis) * time.Millisecond),
+		}
+	}
+}
+
+// expire drops the entry cached for username and endpoint.
+func (ec *endpointCache) expire(username, endpoint string) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	delete(ec.entries, endpointKey{username, endpoint})
+}
+
+// clear drops every cached entry.
+func (ec *endpointCache) clear() {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.entries = nil
+}
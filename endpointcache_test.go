@@ -0,0 +1,53 @@
+package casper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Test EndpointCache.
+func TestEndpointCache(t *testing.T) {
+	var paramTests = []struct {
+		cacheMillis       int
+		forceExpireCached bool
+		expire            bool
+		expectation       int32
+	}{
+		{60000, false, false, 1},
+		{0, false, false, 3},
+		{60000, true, false, 3},
+		{60000, false, true, 3},
+	}
+
+	for _, test := range paramTests {
+		test := test
+		var casperRequests int32
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte(`{}`))
+		})
+		casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&casperRequests, 1)
+			claims := testClaims(req)
+			fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"cache_millis": %d, "endpoint": "%s", "params": {"req_token": "test_req_token"}}], "settings": {"force_expire_cached": %t}}`, test.cacheMillis, claims["endpoint"], test.forceExpireCached)
+		}))
+		testCasperClient.CasperURL = casperServer.URL
+
+		for i := 0; i < 3; i++ {
+			if test.expire {
+				testCasperClient.ExpireEndpointCache()
+			}
+			if _, err := testCasperClient.Call(context.Background(), "/loq/all_updates", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if result := atomic.LoadInt32(&casperRequests); result != test.expectation {
+			t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", "/loq/all_updates", test.expectation, result)
+		}
+		casperServer.Close()
+		closeServers()
+	}
+}
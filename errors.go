@@ -0,0 +1,147 @@
+package casper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Casper error messages.
+// A new Error is created for every failure, so errors are never shared between requests.
+const (
+	casperParseError       = "casper: CasperParseError"
+	casperHTTPError        = "casper: CasperHTTPError"
+	casperSignatureError   = "casper: CasperSignatureError"
+	casperAuthError        = "casper: CasperAuthError"
+	casperAuthExpiredError = "casper: CasperAuthExpiredError"
+	casperRateLimitError   = "casper: CasperRateLimitError"
+	casperDeprecatedError  = "casper: CasperDeprecatedError"
+	casperEndpointError    = "casper: CasperEndpointError"
+	casperSessionError     = "casper: CasperSessionError"
+	snapchatError          = "snapchat: SnapchatError"
+)
+
+// Errors that can be matched with errors.Is against any error returned by casper methods.
+var (
+	// ErrParse reports a response body that could not be read or decoded.
+	ErrParse = Error{Err: casperParseError}
+	// ErrHTTP reports a failed request to the Casper API or to Snapchat.
+	ErrHTTP = Error{Err: casperHTTPError}
+	// ErrSignature reports a request the Casper API could not verify, usually a wrong APISecret.
+	ErrSignature = Error{Err: casperSignatureError}
+	// ErrAuth reports a method called without an auth token or username.
+	ErrAuth = Error{Err: casperAuthError}
+	// ErrAuthExpired reports an auth token Snapchat no longer accepts.
+	ErrAuthExpired = Error{Err: casperAuthExpiredError}
+	// ErrRateLimited reports a request throttled by the Casper API or by Snapchat.
+	ErrRateLimited = Error{Err: casperRateLimitError}
+	// ErrDeprecated reports a method that no longer works.
+	ErrDeprecated = Error{Err: casperDeprecatedError}
+	// ErrEndpoint reports an endpointauth response missing the endpoint that was asked for.
+	ErrEndpoint = Error{Err: casperEndpointError}
+	// ErrSession reports a saved session that could not be restored, usually a wrong passphrase.
+	ErrSession = Error{Err: casperSessionError}
+	// ErrSnapchat reports a request Snapchat answered with an error, see SnapchatError for details.
+	ErrSnapchat = Error{Err: snapchatError}
+)
+
+// Error handles errors returned by casper methods.
+type Error struct {
+	Err    string
+	Reason error
+}
+
+// Error is a function which CasperError satisfies.
+// It returns a properly formatted error message when an error occurs.
+func (e Error) Error() string {
+	if e.Reason == nil {
+		return e.Err
+	}
+	return fmt.Sprintf("%s\nReason: %s", e.Err, e.Reason.Error())
+}
+
+// Unwrap returns the reason of the error, so errors.Is and errors.As can inspect it.
+func (e Error) Unwrap() error {
+	return e.Reason
+}
+
+// Is reports whether target is an Error of the same kind as e, regardless of their reasons.
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.Err == e.Err
+}
+
+// Error returns the message and status of a Snapchat error.
+func (e SnapchatError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = "Something went wrong"
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("snapchat: %s (status %d, %d %s)", message, e.Status, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("snapchat: %s (status %d)", message, e.Status)
+}
+
+// Is reports whether e matches target.
+// Every SnapchatError matches ErrSnapchat, rejected auth tokens match ErrAuthExpired
+// and throttled requests match ErrRateLimited.
+func (e SnapchatError) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	switch t.Err {
+	case snapchatError:
+		return true
+	case casperAuthExpiredError:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case casperRateLimitError:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// Error returns the message and status code of a Casper API error.
+func (e APIErrorResponseModel) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Code, http.StatusText(e.Code))
+}
+
+// apiError creates an Error of the right kind for the Casper API error model.
+func apiError(model APIErrorResponseModel) Error {
+	switch {
+	case model.Code == http.StatusTooManyRequests:
+		return Error{Err: casperRateLimitError, Reason: model}
+	case strings.Contains(model.Message, "Signature verification failed"):
+		return Error{Err: casperSignatureError, Reason: model}
+	}
+	return Error{Err: casperHTTPError, Reason: model}
+}
+
+// snapchatResponseError returns a SnapchatError when the Snapchat response res with body
+// is not successful, either because of a non 2xx status code or a {"logged": false, "status": -x} payload.
+func snapchatResponseError(res *http.Response, body []byte) error {
+	var payload struct {
+		Message string `json:"message"`
+		Status  int    `json:"status"`
+		Logged  *bool  `json:"logged"`
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		json.Unmarshal(trimmed, &payload)
+	}
+	scErr := SnapchatError{
+		Message:    payload.Message,
+		Status:     payload.Status,
+		StatusCode: res.StatusCode,
+		RetryAfter: parseRetryAfter(res.Header),
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return scErr
+	}
+	if payload.Logged != nil && !*payload.Logged && payload.Status < 0 {
+		return scErr
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// Test ErrorIs.
+func TestErrorIs(t *testing.T) {
+	var paramTests = []struct {
+		err         error
+		target      error
+		expectation bool
+	}{
+		{Error{Err: casperHTTPError, Reason: errors.New("connection refused")}, ErrHTTP, true},
+		{Error{Err: casperHTTPError, Reason: errors.New("connection refused")}, ErrParse, false},
+		{Error{Err: casperHTTPError, Reason: context.Canceled}, context.Canceled, true},
+		{apiError(APIErrorResponseModel{Code: 400, Message: "JWT Exception: Signature verification failed"}), ErrSignature, true},
+		{apiError(APIErrorResponseModel{Code: 429, Message: "Too many requests"}), ErrRateLimited, true},
+		{apiError(APIErrorResponseModel{Code: 500, Message: "Internal error"}), ErrHTTP, true},
+		{SnapchatError{StatusCode: 401}, ErrAuthExpired, true},
+		{SnapchatError{StatusCode: 401}, ErrSnapchat, true},
+		{SnapchatError{StatusCode: 429}, ErrRateLimited, true},
+		{SnapchatError{StatusCode: 500}, ErrAuthExpired, false},
+		{fmt.Errorf("wrapped: %w", SnapchatError{StatusCode: 403}), ErrAuthExpired, true},
+		{ErrDeprecated, ErrDeprecated, true},
+	}
+
+	for _, test := range paramTests {
+		result := errors.Is(test.err, test.target)
+		if result != test.expectation {
+			t.Errorf("errors.Is(%q, %q) failed test. \n\n\rWant: \n\r\"%t\" \n\rGot: \n\r\"%t\" \n\n", test.err, test.target, test.expectation, result)
+		}
+	}
+}
+
+// Test ErrorAs.
+func TestErrorAs(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+	})
+	defer closeServers()
+
+	_, err := testCasperClient.Logout()
+	var snapchatErr SnapchatError
+	if !errors.As(err, &snapchatErr) {
+		t.Fatalf("Logout() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", "SnapchatError", err)
+	}
+	if snapchatErr.StatusCode != http.StatusUnauthorized || !errors.Is(err, ErrAuthExpired) {
+		t.Errorf("Logout() failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", http.StatusUnauthorized, snapchatErr.StatusCode)
+	}
+}
+
+// Test SnapchatResponseError.
+func TestSnapchatResponseError(t *testing.T) {
+	var paramTests = []struct {
+		statusCode  int
+		body        string
+		expectation error
+	}{
+		{200, `{"updates_response": {}}`, nil},
+		{200, `{"logged": true, "message": "Friend added", "status": 0}`, nil},
+		{200, "PK\x03\x04", nil},
+		{204, "", nil},
+		{200, `{"logged": false, "message": "Incorrect password", "status": -100}`, SnapchatError{Message: "Incorrect password", Status: -100, StatusCode: 200}},
+		{401, `{"logged": false, "message": "Auth token expired", "status": -100}`, SnapchatError{Message: "Auth token expired", Status: -100, StatusCode: 401}},
+		{500, "<html>Internal Server Error</html>", SnapchatError{StatusCode: 500}},
+	}
+
+	for _, test := range paramTests {
+		res := &http.Response{StatusCode: test.statusCode, Header: http.Header{}}
+		result := snapchatResponseError(res, []byte(test.body))
+		if result != test.expectation {
+			t.Errorf("snapchatResponseError(%d, %q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", test.statusCode, test.body, test.expectation, result)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package casper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrency is how many accounts Manager.ForEach works on at once when Manager.Concurrency is not set.
+const DefaultConcurrency = 4
+
+// Requests per second and burst of the CasperLimiter created by NewManager.
+const (
+	DefaultCasperRate  = 5
+	DefaultCasperBurst = 10
+)
+
+// Manager holds the Casper clients of several Snapchat accounts keyed by username.
+// Every client added to a Manager uses its API key and shares its HTTPClient and CasperLimiter.
+// Change HTTPClient or CasperLimiter before adding clients, clients added earlier keep the previous ones.
+// A Manager is safe for concurrent use.
+type Manager struct {
+	APIKey        string
+	APISecret     string
+	HTTPClient    *http.Client
+	CasperLimiter *RateLimiter
+	Concurrency   int
+
+	mu      sync.RWMutex
+	clients map[string]*Casper
+}
+
+// managerSessions is the versioned JSON document written by Manager.MarshalSessions.
+type managerSessions struct {
+	Version  int                        `json:"version"`
+	Sessions map[string]json.RawMessage `json:"sessions"`
+}
+
+// AccountErrors maps the usernames of accounts to the errors Manager.ForEach got for them.
+type AccountErrors map[string]error
+
+// Error lists every failed account and its error, sorted by username.
+func (e AccountErrors) Error() string {
+	var failures []string
+	for _, username := range e.usernames() {
+		failures = append(failures, username+": "+e[username].Error())
+	}
+	return "casper: " + strings.Join(failures, "; ")
+}
+
+// Is reports whether the error of any failed account matches target.
+func (e AccountErrors) Is(target error) bool {
+	for _, username := range e.usernames() {
+		if errors.Is(e[username], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of a failed account, sorted by username, that matches target and sets target to it.
+func (e AccountErrors) As(target interface{}) bool {
+	for _, username := range e.usernames() {
+		if errors.As(e[username], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// usernames returns the usernames of the failed accounts, sorted.
+func (e AccountErrors) usernames() []string {
+	var usernames []string
+	for username := range e {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// NewManager returns a Manager using apiKey and apiSecret, sharing a HTTP client with the default timeouts
+// and a CasperLimiter allowing DefaultCasperRate requests per second in bursts of DefaultCasperBurst.
+func NewManager(apiKey string, apiSecret string) *Manager {
+	return &Manager{
+		APIKey:        apiKey,
+		APISecret:     apiSecret,
+		HTTPClient:    newHTTPClient(nil),
+		CasperLimiter: NewRateLimiter(DefaultCasperRate, DefaultCasperBurst),
+	}
+}
+
+// Add adds c to m under c.Username, replacing any client of the same account.
+// The API key, HTTP client and Casper limiter of m are set on c where c has none.
+func (m *Manager) Add(c *Casper) {
+	if c.APIKey == "" {
+		c.APIKey = m.APIKey
+		c.APISecret = m.APISecret
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = m.HTTPClient
+	}
+	if c.CasperLimiter == nil {
+		c.CasperLimiter = m.CasperLimiter
+	}
+	username, _ := c.account()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.clients == nil {
+		m.clients = make(map[string]*Casper)
+	}
+	m.clients[username] = c
+}
+
+// Get returns the client of username.
+func (m *Manager) Get(username string) (*Casper, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.clients[username]
+	return c, ok
+}
+
+// Remove removes the client of username from m.
+func (m *Manager) Remove(username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, username)
+}
+
+// Usernames returns the usernames of every account in m, sorted.
+func (m *Manager) Usernames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var usernames []string
+	for username := range m.clients {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// ForEach calls fn with the client of every account in m, running at most m.Concurrency calls at once.
+// Every account is visited even when some fail, unless ctx is done first.
+// Failures are returned as AccountErrors.
+func (m *Manager) ForEach(ctx context.Context, fn func(*Casper) error) error {
+	concurrency := m.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = AccountErrors{}
+		sem  = make(chan struct{}, concurrency)
+	)
+	for _, username := range m.Usernames() {
+		c, ok := m.Get(username)
+		if !ok {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs[username] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(username string, c *Casper) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(c); err != nil {
+				mu.Lock()
+				errs[username] = err
+				mu.Unlock()
+			}
+		}(username, c)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MarshalSessions returns the sessions of every account in m as versioned JSON,
+// each encrypted with passphrase when it is not empty. See Casper.MarshalSession.
+func (m *Manager) MarshalSessions(passphrase string) ([]byte, error) {
+	file := managerSessions{
+		Version:  SessionVersion,
+		Sessions: make(map[string]json.RawMessage),
+	}
+	for _, username := range m.Usernames() {
+		c, ok := m.Get(username)
+		if !ok {
+			continue
+		}
+		data, err := c.MarshalSession(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("casper: %s: %w", username, err)
+		}
+		file.Sessions[username] = data
+	}
+	return json.Marshal(file)
+}
+
+// RestoreSessions adds a client to m for every session written by MarshalSessions.
+func (m *Manager) RestoreSessions(data []byte, passphrase string) error {
+	var file managerSessions
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Error{Err: casperSessionError, Reason: err}
+	}
+	if file.Version != SessionVersion {
+		return Error{Err: casperSessionError, Reason: fmt.Errorf("unsupported session version %d", file.Version)}
+	}
+	for username, session := range file.Sessions {
+		c := &Casper{}
+		if err := c.RestoreSession(session, passphrase); err != nil {
+			return fmt.Errorf("casper: %s: %w", username, err)
+		}
+		m.Add(c)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test Manager.ForEach.
+func TestManagerForEach(t *testing.T) {
+	var paramTests = []struct {
+		accounts    int
+		concurrency int
+		failures    int
+	}{
+		{10, 1, 0},
+		{10, 3, 2},
+		{2, 0, 1},
+	}
+
+	for _, test := range paramTests {
+		manager := NewManager(testCasperKeys.TestAPIKey, testCasperKeys.TestAPISecret)
+		manager.Concurrency = test.concurrency
+		for i := 0; i < test.accounts; i++ {
+			manager.Add(&Casper{
+				Username:  fmt.Sprintf("test_api_user_%d", i),
+				AuthToken: "test_auth_token",
+			})
+		}
+
+		var running, maxRunning int32
+		err := manager.ForEach(context.Background(), func(c *Casper) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				prev := atomic.LoadInt32(&maxRunning)
+				if n <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if c.HTTPClient != manager.HTTPClient || c.CasperLimiter == nil || c.CasperLimiter != manager.CasperLimiter || c.APIKey != testCasperKeys.TestAPIKey {
+				t.Errorf("Manager.Add(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", c.Username, "shared client", "own client")
+			}
+			for i := 0; i < test.failures; i++ {
+				if c.Username == fmt.Sprintf("test_api_user_%d", i) {
+					return ErrHTTP
+				}
+			}
+			return nil
+		})
+
+		concurrency := int32(test.concurrency)
+		if concurrency < 1 {
+			concurrency = DefaultConcurrency
+		}
+		if result := atomic.LoadInt32(&maxRunning); result > concurrency {
+			t.Errorf("Manager.ForEach(%d) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", test.concurrency, concurrency, result)
+		}
+		var accountErrs AccountErrors
+		errors.As(err, &accountErrs)
+		var casperErr Error
+		if len(accountErrs) != test.failures || (test.failures > 0 && (!errors.Is(err, ErrHTTP) || !errors.As(err, &casperErr))) {
+			t.Errorf("Manager.ForEach(%d) failed test. \n\n\rWant: \n\r\"%d failures\" \n\rGot: \n\r\"%v\" \n\n", test.concurrency, test.failures, err)
+		}
+	}
+}
+
+// Test Manager sessions.
+func TestManagerSessions(t *testing.T) {
+	manager := NewManager(testCasperKeys.TestAPIKey, testCasperKeys.TestAPISecret)
+	usernames := []string{"test_api_user_0", "test_api_user_1"}
+	for _, username := range usernames {
+		manager.Add(&Casper{
+			Username:  username,
+			AuthToken: "test_auth_token_" + username,
+		})
+	}
+	data, err := manager.MarshalSessions("test_passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewManager(testCasperKeys.TestAPIKey, testCasperKeys.TestAPISecret)
+	if err := restored.RestoreSessions(data, "test_passphrase"); err != nil {
+		t.Fatalf("Manager.RestoreSessions(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "test_passphrase", "<nil>", err)
+	}
+	for _, username := range usernames {
+		c, ok := restored.Get(username)
+		if !ok || c.AuthToken != "test_auth_token_"+username || c.HTTPClient != restored.HTTPClient {
+			t.Errorf("Manager.RestoreSessions(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", "test_passphrase", username, c)
+		}
+	}
+	if err := NewManager("", "").RestoreSessions(data, "wrong_passphrase"); !errors.Is(err, ErrSession) {
+		t.Errorf("Manager.RestoreSessions(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "wrong_passphrase", ErrSession, err)
+	}
+}
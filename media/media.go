@@ -0,0 +1,292 @@
+// Package media provides functions for encrypting and decrypting Snapchat media blobs.
+//
+// Snaps sent directly to users are encrypted with AES-128 in ECB mode using a key shared by every client,
+// stories are encrypted with AES in CBC mode using the media_key and media_iv Snapchat sends with each story.
+// Both are padded with PKCS#7.
+//
+// Video snaps with an overlay are sent as zip archives holding a media~ and an overlay~ entry,
+// see Zip and Unzip.
+package media
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// BlobKey is the AES key Snapchat uses to encrypt snaps sent directly to users.
+var BlobKey = []byte("M02cnQ51Ji97vwT4")
+
+// Errors returned when decrypting media.
+var (
+	ErrBlockSize = errors.New("media: encrypted data is not a multiple of the block size")
+	ErrPadding   = errors.New("media: invalid padding")
+)
+
+// readChunkSize is how much encrypted data a decrypting reader reads at once.
+const readChunkSize = 32 * 1024
+
+// EncryptSnap reads media from r, encrypts it as a snap and writes it to w.
+func EncryptSnap(w io.Writer, r io.Reader) error {
+	ew, err := NewECBWriter(w, BlobKey)
+	if err != nil {
+		return err
+	}
+	return copyAndClose(ew, r)
+}
+
+// DecryptSnap reads an encrypted snap from r, decrypts it and writes the media to w.
+func DecryptSnap(w io.Writer, r io.Reader) error {
+	dr, err := NewECBReader(r, BlobKey)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+	return err
+}
+
+// EncryptStory reads media from r, encrypts it as a story with the base64 encoded key and iv and writes it to w.
+func EncryptStory(w io.Writer, r io.Reader, key, iv string) error {
+	k, i, err := decodeKeyIV(key, iv)
+	if err != nil {
+		return err
+	}
+	ew, err := NewCBCWriter(w, k, i)
+	if err != nil {
+		return err
+	}
+	return copyAndClose(ew, r)
+}
+
+// DecryptStory reads an encrypted story from r, decrypts it with the base64 encoded key and iv
+// found in the story's media_key and media_iv fields and writes the media to w.
+func DecryptStory(w io.Writer, r io.Reader, key, iv string) error {
+	k, i, err := decodeKeyIV(key, iv)
+	if err != nil {
+		return err
+	}
+	dr, err := NewCBCReader(r, k, i)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+	return err
+}
+
+// NewECBWriter returns a writer encrypting everything written to it with key in ECB mode before writing it to w.
+// The final block is padded and written when the writer is closed.
+func NewECBWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockWriter(w, ecbEncrypter{block}), nil
+}
+
+// NewECBReader returns a reader decrypting everything read from r with key in ECB mode.
+func NewECBReader(r io.Reader, key []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockReader(r, ecbDecrypter{block}), nil
+}
+
+// NewCBCWriter returns a writer encrypting everything written to it with key and iv in CBC mode before writing it to w.
+// The final block is padded and written when the writer is closed.
+func NewCBCWriter(w io.Writer, key, iv []byte) (io.WriteCloser, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("media: iv length must equal the block size")
+	}
+	return newBlockWriter(w, cipher.NewCBCEncrypter(block, iv)), nil
+}
+
+// NewCBCReader returns a reader decrypting everything read from r with key and iv in CBC mode.
+func NewCBCReader(r io.Reader, key, iv []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("media: iv length must equal the block size")
+	}
+	return newBlockReader(r, cipher.NewCBCDecrypter(block, iv)), nil
+}
+
+// blockWriter encrypts whole blocks as they are written and pads the last one on Close.
+type blockWriter struct {
+	w    io.Writer
+	mode cipher.BlockMode
+	buf  []byte
+}
+
+func newBlockWriter(w io.Writer, mode cipher.BlockMode) *blockWriter {
+	return &blockWriter{w: w, mode: mode}
+}
+
+// Write encrypts and writes every complete block of p, keeping the remainder for the next call.
+func (bw *blockWriter) Write(p []byte) (int, error) {
+	bw.buf = append(bw.buf, p...)
+	n := len(bw.buf) - len(bw.buf)%bw.mode.BlockSize()
+	if n == 0 {
+		return len(p), nil
+	}
+	out := make([]byte, n)
+	bw.mode.CryptBlocks(out, bw.buf[:n])
+	bw.buf = append(bw.buf[:0], bw.buf[n:]...)
+	if _, err := bw.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close pads, encrypts and writes the final block. It does not close the underlying writer.
+func (bw *blockWriter) Close() error {
+	padded := pad(bw.buf, bw.mode.BlockSize())
+	bw.mode.CryptBlocks(padded, padded)
+	bw.buf = nil
+	_, err := bw.w.Write(padded)
+	return err
+}
+
+// blockReader decrypts blocks as they are read, holding back the last block until EOF so its padding can be removed.
+type blockReader struct {
+	r    io.Reader
+	mode cipher.BlockMode
+	buf  []byte
+	out  []byte
+	err  error
+}
+
+func newBlockReader(r io.Reader, mode cipher.BlockMode) *blockReader {
+	return &blockReader{r: r, mode: mode}
+}
+
+// Read reads decrypted media into p.
+func (br *blockReader) Read(p []byte) (int, error) {
+	for len(br.out) == 0 && br.err == nil {
+		br.fill()
+	}
+	if len(br.out) == 0 {
+		return 0, br.err
+	}
+	n := copy(p, br.out)
+	br.out = br.out[n:]
+	return n, nil
+}
+
+// fill reads the next chunk of encrypted data and decrypts every block that is known not to be the last one.
+func (br *blockReader) fill() {
+	bs := br.mode.BlockSize()
+	chunk := make([]byte, readChunkSize)
+	n, err := br.r.Read(chunk)
+	br.buf = append(br.buf, chunk[:n]...)
+	if err != nil && err != io.EOF {
+		br.err = err
+		return
+	}
+	if err == io.EOF {
+		if len(br.buf) == 0 || len(br.buf)%bs != 0 {
+			br.err = ErrBlockSize
+			return
+		}
+		br.mode.CryptBlocks(br.buf, br.buf)
+		br.out, br.err = unpad(br.buf, bs)
+		br.buf = nil
+		if br.err == nil {
+			br.err = io.EOF
+		}
+		return
+	}
+	// Keep at least one whole block back, it may be the padded one.
+	n = len(br.buf) - len(br.buf)%bs - bs
+	if n <= 0 {
+		return
+	}
+	br.out = make([]byte, n)
+	br.mode.CryptBlocks(br.out, br.buf[:n])
+	br.buf = append(br.buf[:0], br.buf[n:]...)
+}
+
+// ecbEncrypter encrypts every block independently with block.
+type ecbEncrypter struct {
+	block cipher.Block
+}
+
+func (e ecbEncrypter) BlockSize() int { return e.block.BlockSize() }
+
+func (e ecbEncrypter) CryptBlocks(dst, src []byte) {
+	bs := e.block.BlockSize()
+	for i := 0; i < len(src); i += bs {
+		e.block.Encrypt(dst[i:i+bs], src[i:i+bs])
+	}
+}
+
+// ecbDecrypter decrypts every block independently with block.
+type ecbDecrypter struct {
+	block cipher.Block
+}
+
+func (d ecbDecrypter) BlockSize() int { return d.block.BlockSize() }
+
+func (d ecbDecrypter) CryptBlocks(dst, src []byte) {
+	bs := d.block.BlockSize()
+	for i := 0; i < len(src); i += bs {
+		d.block.Decrypt(dst[i:i+bs], src[i:i+bs])
+	}
+}
+
+// pad returns a copy of b padded to a multiple of blockSize with PKCS#7.
+func pad(b []byte, blockSize int) []byte {
+	n := blockSize - len(b)%blockSize
+	padded := make([]byte, len(b)+n)
+	copy(padded, b)
+	for i := len(b); i < len(padded); i++ {
+		padded[i] = byte(n)
+	}
+	return padded
+}
+
+// unpad removes the PKCS#7 padding from b.
+func unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, ErrPadding
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > blockSize || n > len(b) {
+		return nil, ErrPadding
+	}
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, ErrPadding
+		}
+	}
+	return b[:len(b)-n], nil
+}
+
+// decodeKeyIV decodes a base64 encoded story key and iv.
+func decodeKeyIV(key, iv string) ([]byte, []byte, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	i, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, i, nil
+}
+
+// copyAndClose copies r to wc and closes wc.
+func copyAndClose(wc io.WriteCloser, r io.Reader) error {
+	if _, err := io.Copy(wc, r); err != nil {
+		return err
+	}
+	return wc.Close()
+}
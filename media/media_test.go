@@ -0,0 +1,106 @@
+package media
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"testing/iotest"
+)
+
+// sequence returns n bytes counting up from 0.
+func sequence(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test Snap.
+func TestSnap(t *testing.T) {
+	var paramTests = []struct {
+		plaintext   []byte
+		expectation string
+	}{
+		{[]byte("hello snapchat"), "b8fe0d12628f233c3a5193dcd02da2ef"},
+		{sequence(100), "1deec0866d4b34003091f9febdb0acb56e737e64970d202e01faffcf1bae5eaff76f4726f5da7a6e67e6863d8a677e0a9ff508d9936c785a13c46f1c2ee25a8860bda7434cb0aaec851d3ff9ff8461cbc7d6ff2e7a06a28770b34ebda5802748cd71f05f3c0297f9524630bebf621762"},
+	}
+
+	for _, test := range paramTests {
+		var encrypted bytes.Buffer
+		if err := EncryptSnap(&encrypted, iotest.OneByteReader(bytes.NewReader(test.plaintext))); err != nil {
+			t.Fatalf("EncryptSnap(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.plaintext, "<nil>", err)
+		}
+		if result := hex.EncodeToString(encrypted.Bytes()); result != test.expectation {
+			t.Errorf("EncryptSnap(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.plaintext, test.expectation, result)
+		}
+
+		var decrypted bytes.Buffer
+		if err := DecryptSnap(&decrypted, iotest.HalfReader(bytes.NewReader(mustHex(test.expectation)))); err != nil {
+			t.Fatalf("DecryptSnap(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.expectation, "<nil>", err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), test.plaintext) {
+			t.Errorf("DecryptSnap(%q) failed test. \n\n\rWant: \n\r\"%x\" \n\rGot: \n\r\"%x\" \n\n", test.expectation, test.plaintext, decrypted.Bytes())
+		}
+	}
+}
+
+// Test Story.
+func TestStory(t *testing.T) {
+	// Key and IV from NIST SP 800-38A F.2.1, base64 encoded like Snapchat's media_key and media_iv.
+	key := "K34VFiiu0qar9xWICc9PPA=="
+	iv := "AAECAwQFBgcICQoLDA0ODw=="
+
+	var paramTests = []struct {
+		plaintext   []byte
+		expectation string
+	}{
+		{mustHex("6bc1bee22e409f96e93d7e117393172a"), "7649abac8119b246cee98e9b12e9197d8964e0b149c10b7b682e6e39aaeb731c"},
+		{sequence(100), "7df76b0c1ab899b33e42f047b91b546f1caa8018c80b15b8e7aea82794adcb00bbc1e295910b9de4f1358dcb4213bdd8eefa3154215f4709af46573fc8cb07b9860dc1dd67ddfd952b41e3aa0cc47a9648738534d37e5e29ae2135af7532e41c165eb566150496550141677e48e2441f"},
+	}
+
+	for _, test := range paramTests {
+		var encrypted bytes.Buffer
+		if err := EncryptStory(&encrypted, bytes.NewReader(test.plaintext), key, iv); err != nil {
+			t.Fatalf("EncryptStory(%x) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.plaintext, "<nil>", err)
+		}
+		if result := hex.EncodeToString(encrypted.Bytes()); result != test.expectation {
+			t.Errorf("EncryptStory(%x) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.plaintext, test.expectation, result)
+		}
+
+		var decrypted bytes.Buffer
+		if err := DecryptStory(&decrypted, iotest.OneByteReader(bytes.NewReader(mustHex(test.expectation))), key, iv); err != nil {
+			t.Fatalf("DecryptStory(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.expectation, "<nil>", err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), test.plaintext) {
+			t.Errorf("DecryptStory(%q) failed test. \n\n\rWant: \n\r\"%x\" \n\rGot: \n\r\"%x\" \n\n", test.expectation, test.plaintext, decrypted.Bytes())
+		}
+	}
+}
+
+// Test InvalidBlob.
+func TestInvalidBlob(t *testing.T) {
+	var paramTests = []struct {
+		blob        []byte
+		expectation error
+	}{
+		{[]byte{}, ErrBlockSize},
+		{mustHex("b8fe0d12628f233c3a5193dcd02da2"), ErrBlockSize},
+		{mustHex("69c4e0d86a7b0430d8cdb78070b4c55a"), ErrPadding},
+	}
+
+	for _, test := range paramTests {
+		err := DecryptSnap(&bytes.Buffer{}, bytes.NewReader(test.blob))
+		if err != test.expectation {
+			t.Errorf("DecryptSnap(%x) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", test.blob, test.expectation, err)
+		}
+	}
+}
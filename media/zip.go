@@ -0,0 +1,103 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// PartKind is the kind of a part of a zipped snap.
+type PartKind int
+
+// Kinds of parts found in a zipped snap.
+const (
+	MediaPart PartKind = iota
+	OverlayPart
+)
+
+// Part is a single file extracted from a zipped snap.
+type Part struct {
+	Kind PartKind
+	Name string
+	Data []byte
+}
+
+// ErrNoMedia is returned when a zipped snap holds no media entry.
+var ErrNoMedia = errors.New("media: zipped snap has no media entry")
+
+// IsZipped reports whether the decrypted snap data is a zip archive,
+// as sent for video snaps with an overlay.
+func IsZipped(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// Unzip extracts the media~ and overlay~ entries of a zipped snap, in the order they are stored.
+// Any other entries are skipped.
+func Unzip(data []byte) ([]Part, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var parts []Part
+	hasMedia := false
+	for _, f := range zr.File {
+		var kind PartKind
+		switch {
+		case strings.HasPrefix(f.Name, "media~"):
+			kind = MediaPart
+			hasMedia = true
+		case strings.HasPrefix(f.Name, "overlay~"):
+			kind = OverlayPart
+		default:
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, Part{Kind: kind, Name: f.Name, Data: b})
+	}
+	if !hasMedia {
+		return nil, ErrNoMedia
+	}
+	return parts, nil
+}
+
+// Zip writes a zipped snap holding the media read from media and the overlay image read from overlay to w.
+// The result still has to be encrypted with EncryptSnap before it is uploaded.
+func Zip(w io.Writer, media io.Reader, overlay io.Reader) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	suffix := strings.ToUpper(hex.EncodeToString(b))
+
+	zw := zip.NewWriter(w)
+	entries := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"media~" + suffix, media},
+		{"overlay~" + suffix, overlay},
+	}
+	for _, entry := range entries {
+		fw, err := zw.Create(entry.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(fw, entry.r); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
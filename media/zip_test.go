@@ -0,0 +1,59 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// Test Zip.
+func TestZip(t *testing.T) {
+	video := []byte("test_video_data")
+	overlay := []byte("\x89PNGtest_overlay_data")
+
+	var zipped bytes.Buffer
+	if err := Zip(&zipped, bytes.NewReader(video), bytes.NewReader(overlay)); err != nil {
+		t.Fatalf("Zip() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "<nil>", err)
+	}
+	if !IsZipped(zipped.Bytes()) {
+		t.Fatalf("IsZipped(%q) failed test. \n\n\rWant: \n\r\"%t\" \n\rGot: \n\r\"%t\" \n\n", zipped.Bytes()[:4], true, false)
+	}
+
+	parts, err := Unzip(zipped.Bytes())
+	if err != nil {
+		t.Fatalf("Unzip() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "<nil>", err)
+	}
+
+	var paramTests = []struct {
+		kind PartKind
+		data []byte
+	}{
+		{MediaPart, video},
+		{OverlayPart, overlay},
+	}
+
+	if len(parts) != len(paramTests) {
+		t.Fatalf("Unzip() failed test. \n\n\rWant: \n\r\"%d\" parts \n\rGot: \n\r\"%d\" parts \n\n", len(paramTests), len(parts))
+	}
+	for i, test := range paramTests {
+		if parts[i].Kind != test.kind || !bytes.Equal(parts[i].Data, test.data) {
+			t.Errorf("Unzip() part %d failed test. \n\n\rWant: \n\r\"%d %q\" \n\rGot: \n\r\"%d %q\" \n\n", i, test.kind, test.data, parts[i].Kind, parts[i].Data)
+		}
+	}
+}
+
+// Test UnzipWithoutMedia.
+func TestUnzipWithoutMedia(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	fw, _ := zw.Create("overlay~TEST")
+	fw.Write([]byte("test_overlay_data"))
+	zw.Close()
+
+	if _, err := Unzip(zipped.Bytes()); err != ErrNoMedia {
+		t.Errorf("Unzip() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", ErrNoMedia, err)
+	}
+	if IsZipped([]byte("\xff\xd8\xff\xe0")) {
+		t.Errorf("IsZipped(%q) failed test. \n\n\rWant: \n\r\"%t\" \n\rGot: \n\r\"%t\" \n\n", "\xff\xd8\xff\xe0", false, true)
+	}
+}
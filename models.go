@@ -1,12 +1,16 @@
 package casper
 
+import "time"
+
 // Snapchat Structs
 
 // SnapchatError represents a Snapchat Error.
 type SnapchatError struct {
-	Message string `json:"message"`
-	Status  int    `json:"status"`
-	Logged  bool   `json:"logged"`
+	Message    string        `json:"message"`
+	Status     int           `json:"status"`
+	Logged     bool          `json:"logged"`
+	StatusCode int           `json:"-"`
+	RetryAfter time.Duration `json:"-"`
 }
 
 // StudySettings provides a study setting event struct.
@@ -69,115 +73,35 @@ type Discover struct {
 		Sponsored bool `json:"sponsored"`
 	} `json:"channels"`
 	GenerationTs int64 `json:"generation_ts"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// Friendmoji describes one kind of friend emoji, keyed by its name in Updates.UpdatesResponse.FriendmojiDict.
+type Friendmoji struct {
+	Type            int    `json:"type"`
+	Source          string `json:"source"`
+	DefaultType     int    `json:"default_type"`
+	DefaultVal      string `json:"default_val"`
+	EmojiLegendRank int64  `json:"emoji_legend_rank"`
 }
 
 // Updates represents the entire Snapchat account.
 type Updates struct {
 	UpdatesResponse struct {
-		FriendmojiDict struct {
-			NumberOneBfForTwoWeeks struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"number_one_bf_for_two_weeks"`
-			YourNumberOneBfIsTheirNumberOneBf struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"your_number_one_bf_is_their_number_one_bf"`
-			OnFire struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"on_fire"`
-			NewFriend struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"new_friend"`
-			NumberOneBfForTwoMonths struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"number_one_bf_for_two_months"`
-			YouAreOneOfThereBfButTheyAreNotYour struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"you_are_one_of_there_bf_but_they_are_not_your"`
-			HundredStreak struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int64  `json:"emoji_legend_rank"`
-			} `json:"hundred_streak"`
-			Catwomen struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int64  `json:"emoji_legend_rank"`
-			} `json:"catwomen"`
-			YouShareBF struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"you_share_BF"`
-			OneOfYourBf struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"one_of_your_bf"`
-			Batman struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int64  `json:"emoji_legend_rank"`
-			} `json:"batman"`
-			Rd83Ddc7D struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int64  `json:"emoji_legend_rank"`
-			} `json:"rd83ddc7d"`
-			NumberOneBf struct {
-				Type            int    `json:"type"`
-				Source          string `json:"source"`
-				DefaultType     int    `json:"default_type"`
-				DefaultVal      string `json:"default_val"`
-				EmojiLegendRank int    `json:"emoji_legend_rank"`
-			} `json:"number_one_bf"`
-		} `json:"friendmoji_dict"`
-		Score                        int           `json:"score"`
-		EnableSaveStoryToGallery     bool          `json:"enable_save_story_to_gallery"`
-		NumberOfBestFriends          int           `json:"number_of_best_friends"`
-		Received                     int           `json:"received"`
-		EnableRecordingHintAndroid   bool          `json:"enable_recording_hint_android"`
-		IsVerifiedUser               bool          `json:"is_verified_user"`
-		Requests                     []interface{} `json:"requests"`
-		Username                     string        `json:"username"`
-		Sent                         int           `json:"sent"`
-		RingingSound                 string        `json:"ringing_sound"`
-		VideoThumbnailEnabledAndroid bool          `json:"video_thumbnail_enabled_android"`
+		FriendmojiDict               map[string]Friendmoji `json:"friendmoji_dict"`
+		Score                        int                   `json:"score"`
+		EnableSaveStoryToGallery     bool                  `json:"enable_save_story_to_gallery"`
+		NumberOfBestFriends          int                   `json:"number_of_best_friends"`
+		Received                     int                   `json:"received"`
+		EnableRecordingHintAndroid   bool                  `json:"enable_recording_hint_android"`
+		IsVerifiedUser               bool                  `json:"is_verified_user"`
+		Requests                     []interface{}         `json:"requests"`
+		Username                     string                `json:"username"`
+		Sent                         int                   `json:"sent"`
+		RingingSound                 string                `json:"ringing_sound"`
+		VideoThumbnailEnabledAndroid bool                  `json:"video_thumbnail_enabled_android"`
 		FeatureSettings              struct {
 			FrontFacingFlash                            bool `json:"front_facing_flash"`
 			ReplaySnaps                                 bool `json:"replay_snaps"`
@@ -462,26 +386,9 @@ type Updates struct {
 	FriendsResponse struct {
 		Bests            []interface{} `json:"bests"`
 		FriendsSyncToken string        `json:"friends_sync_token"`
-		Friends          []struct {
-			NeedsLove           bool          `json:"needs_love"`
-			CanSeeCustomStories bool          `json:"can_see_custom_stories"`
-			Expiration          int64         `json:"expiration"`
-			DontDecayThumbnail  bool          `json:"dont_decay_thumbnail,omitempty"`
-			Direction           string        `json:"direction"`
-			Name                string        `json:"name"`
-			FriendmojiString    string        `json:"friendmoji_string"`
-			SharedStoryID       string        `json:"shared_story_id,omitempty"`
-			IsSharedStory       bool          `json:"is_shared_story,omitempty"`
-			Display             string        `json:"display"`
-			Venue               string        `json:"venue,omitempty"`
-			Type                int           `json:"type"`
-			UserID              string        `json:"user_id,omitempty"`
-			Ts                  int           `json:"ts,omitempty"`
-			FriendmojiSymbols   []interface{} `json:"friendmoji_symbols,omitempty"`
-			SnapStreakCount     int           `json:"snap_streak_count,omitempty"`
-		} `json:"friends"`
-		FriendsSyncType string        `json:"friends_sync_type"`
-		AddedFriends    []interface{} `json:"added_friends"`
+		Friends          []FriendInfo  `json:"friends"`
+		FriendsSyncType  string        `json:"friends_sync_type"`
+		AddedFriends     []interface{} `json:"added_friends"`
 	} `json:"friends_response"`
 	StoriesResponse struct {
 		MyStoriesWithCollabs []interface{} `json:"my_stories_with_collabs"`
@@ -491,158 +398,14 @@ type Updates struct {
 			YesText string `json:"yes_text"`
 			NoText  string `json:"no_text"`
 		} `json:"mature_content_text"`
-		MyVerifiedStories  []interface{} `json:"my_verified_stories"`
-		MyStories          []interface{} `json:"my_stories"`
-		FriendStoriesDelta bool          `json:"friend_stories_delta"`
-		FriendStories      []struct {
-			MatureContent bool   `json:"mature_content"`
-			Username      string `json:"username"`
-			Stories       []struct {
-				Story struct {
-					ID            string  `json:"id"`
-					Username      string  `json:"username"`
-					MatureContent bool    `json:"mature_content"`
-					ClientID      string  `json:"client_id"`
-					Timestamp     int64   `json:"timestamp"`
-					MediaID       string  `json:"media_id"`
-					MediaKey      string  `json:"media_key"`
-					MediaIv       string  `json:"media_iv"`
-					ThumbnailIv   string  `json:"thumbnail_iv"`
-					MediaType     int     `json:"media_type"`
-					Time          float64 `json:"time"`
-					Zipped        bool    `json:"zipped"`
-					StoryFilterID string  `json:"story_filter_id"`
-					TimeLeft      int     `json:"time_left"`
-					IsShared      bool    `json:"is_shared"`
-					MediaURL      string  `json:"media_url"`
-					ThumbnailURL  string  `json:"thumbnail_url"`
-					NeedsAuth     bool    `json:"needs_auth"`
-					AdCanFollow   bool    `json:"ad_can_follow"`
-				} `json:"story"`
-				Viewed           bool   `json:"viewed"`
-				FlushableStoryID string `json:"flushable_story_id"`
-			} `json:"stories"`
-			AllowStoryExplorer  bool   `json:"allow_story_explorer,omitempty"`
-			DisplayName         string `json:"display_name,omitempty"`
-			ProfileDescription  string `json:"profile_description,omitempty"`
-			AdPlacementMetadata struct {
-				AdInsertionConfig struct {
-					FirstOnResume   int `json:"first_on_resume"`
-					Interval        int `json:"interval"`
-					MinSnapsAfterAd int `json:"min_snaps_after_ad"`
-					FirstOnStart    int `json:"first_on_start"`
-				} `json:"ad_insertion_config"`
-				AdRequestConfig struct {
-					FirstPosition    int `json:"first_position"`
-					MinimumRemaining int `json:"minimum_remaining"`
-					Timeout          int `json:"timeout"`
-				} `json:"ad_request_config"`
-				TargetingParameters struct {
-					Genre                   string `json:"genre"`
-					InventoryFullyQualified string `json:"inventory_fully_qualified"`
-					ChannelID               string `json:"channel_id"`
-					ChannelType             string `json:"channel_type"`
-				} `json:"targeting_parameters"`
-				AdUnitID string `json:"ad_unit_id"`
-			} `json:"ad_placement_metadata,omitempty"`
-			SharedID             string `json:"shared_id,omitempty"`
-			HasCustomDescription bool   `json:"has_custom_description,omitempty"`
-			Thumbnails           struct {
-				Unviewed struct {
-					NeedsAuth bool   `json:"needs_auth"`
-					URL       string `json:"url"`
-				} `json:"unviewed"`
-				Viewed struct {
-					NeedsAuth bool   `json:"needs_auth"`
-					URL       string `json:"url"`
-				} `json:"viewed"`
-			} `json:"thumbnails,omitempty"`
-			IsLocal bool `json:"is_local,omitempty"`
-		} `json:"friend_stories"`
-		MyGroupStories []interface{} `json:"my_group_stories"`
+		MyVerifiedStories  []interface{}      `json:"my_verified_stories"`
+		MyStories          []interface{}      `json:"my_stories"`
+		FriendStoriesDelta bool               `json:"friend_stories_delta"`
+		FriendStories      []FriendStoryGroup `json:"friend_stories"`
+		MyGroupStories     []interface{}      `json:"my_group_stories"`
 	} `json:"stories_response"`
-	ConversationsResponse []struct {
-		Participants         []string      `json:"participants"`
-		LastInteractionTs    int64         `json:"last_interaction_ts"`
-		PendingChatsFor      []interface{} `json:"pending_chats_for"`
-		PendingReceivedSnaps []struct {
-			Sn                     string  `json:"sn"`
-			T                      int     `json:"t"`
-			Timer                  float64 `json:"timer"`
-			Mo                     int     `json:"mo"`
-			Broadcast              int     `json:"broadcast"`
-			BroadcastMediaURL      string  `json:"broadcast_media_url"`
-			BroadcastSecondaryText string  `json:"broadcast_secondary_text,omitempty"`
-			BroadcastHideTimer     bool    `json:"broadcast_hide_timer"`
-			EsID                   string  `json:"es_id"`
-			ID                     string  `json:"id"`
-			St                     int     `json:"st"`
-			M                      int     `json:"m"`
-			Ts                     int64   `json:"ts"`
-			Sts                    int64   `json:"sts"`
-		} `json:"pending_received_snaps"`
-		ID                   string `json:"id"`
-		ConversationMessages struct {
-			MessagingAuth struct {
-				Payload string `json:"payload"`
-				Mac     string `json:"mac"`
-				Type    string `json:"type"`
-			} `json:"messaging_auth"`
-			Messages []struct {
-				Snap struct {
-					Sn                 string  `json:"sn"`
-					T                  int     `json:"t"`
-					Timer              float64 `json:"timer"`
-					Mo                 int     `json:"mo"`
-					Broadcast          int     `json:"broadcast"`
-					BroadcastMediaURL  string  `json:"broadcast_media_url"`
-					BroadcastHideTimer bool    `json:"broadcast_hide_timer"`
-					EsID               string  `json:"es_id"`
-					ID                 string  `json:"id"`
-					St                 int     `json:"st"`
-					M                  int     `json:"m"`
-					Ts                 int64   `json:"ts"`
-					Sts                int64   `json:"sts"`
-				} `json:"snap"`
-			} `json:"messages"`
-		} `json:"conversation_messages"`
-		ConversationState struct {
-			UserSequences struct {
-				Teamsnapchat int `json:"teamsnapchat"`
-			} `json:"user_sequences"`
-			UserChatReleases struct {
-				Teamsnapchat struct {
-					Teamsnapchat int `json:"teamsnapchat"`
-				} `json:"teamsnapchat"`
-				Antoinette2A47 struct {
-					Teamsnapchat int `json:"teamsnapchat"`
-				} `json:"antoinette2a47"`
-			} `json:"user_chat_releases"`
-			UserSnapReleases struct {
-			} `json:"user_snap_releases"`
-		} `json:"conversation_state"`
-		LastSnap struct {
-			Sn                 string  `json:"sn"`
-			T                  int     `json:"t"`
-			Timer              float64 `json:"timer"`
-			Mo                 int     `json:"mo"`
-			Broadcast          int     `json:"broadcast"`
-			BroadcastMediaURL  string  `json:"broadcast_media_url"`
-			BroadcastHideTimer bool    `json:"broadcast_hide_timer"`
-			EsID               string  `json:"es_id"`
-			ID                 string  `json:"id"`
-			St                 int     `json:"st"`
-			M                  int     `json:"m"`
-			Ts                 int64   `json:"ts"`
-			Sts                int64   `json:"sts"`
-		} `json:"last_snap"`
-		LastChatActions struct {
-			LastWriter         string `json:"last_writer"`
-			LastWriteTimestamp int64  `json:"last_write_timestamp"`
-			LastWriteType      string `json:"last_write_type"`
-		} `json:"last_chat_actions"`
-	} `json:"conversations_response"`
-	Discover struct {
+	ConversationsResponse []Conversation `json:"conversations_response"`
+	Discover              struct {
 		Compatibility          string `json:"compatibility"`
 		GetChannels            string `json:"get_channels"`
 		VideoCatalog           string `json:"video_catalog"`
@@ -717,32 +480,181 @@ type Updates struct {
 	Dtoken1V string `json:"dtoken1v"`
 }
 
+// FriendInfo holds a Snapchat friend as listed in Updates.FriendsResponse.
+type FriendInfo struct {
+	NeedsLove           bool          `json:"needs_love"`
+	CanSeeCustomStories bool          `json:"can_see_custom_stories"`
+	Expiration          int64         `json:"expiration"`
+	DontDecayThumbnail  bool          `json:"dont_decay_thumbnail,omitempty"`
+	Direction           string        `json:"direction"`
+	Name                string        `json:"name"`
+	FriendmojiString    string        `json:"friendmoji_string"`
+	SharedStoryID       string        `json:"shared_story_id,omitempty"`
+	IsSharedStory       bool          `json:"is_shared_story,omitempty"`
+	Display             string        `json:"display"`
+	Venue               string        `json:"venue,omitempty"`
+	Type                int           `json:"type"`
+	UserID              string        `json:"user_id,omitempty"`
+	Ts                  int           `json:"ts,omitempty"`
+	FriendmojiSymbols   []interface{} `json:"friendmoji_symbols,omitempty"`
+	SnapStreakCount     int           `json:"snap_streak_count,omitempty"`
+}
+
+// FriendStoryGroup holds the stories of one friend as listed in Updates.StoriesResponse.
+type FriendStoryGroup struct {
+	MatureContent bool   `json:"mature_content"`
+	Username      string `json:"username"`
+	Stories       []struct {
+		Story            Story  `json:"story"`
+		Viewed           bool   `json:"viewed"`
+		FlushableStoryID string `json:"flushable_story_id"`
+	} `json:"stories"`
+	AllowStoryExplorer  bool   `json:"allow_story_explorer,omitempty"`
+	DisplayName         string `json:"display_name,omitempty"`
+	ProfileDescription  string `json:"profile_description,omitempty"`
+	AdPlacementMetadata struct {
+		AdInsertionConfig struct {
+			FirstOnResume   int `json:"first_on_resume"`
+			Interval        int `json:"interval"`
+			MinSnapsAfterAd int `json:"min_snaps_after_ad"`
+			FirstOnStart    int `json:"first_on_start"`
+		} `json:"ad_insertion_config"`
+		AdRequestConfig struct {
+			FirstPosition    int `json:"first_position"`
+			MinimumRemaining int `json:"minimum_remaining"`
+			Timeout          int `json:"timeout"`
+		} `json:"ad_request_config"`
+		TargetingParameters struct {
+			Genre                   string `json:"genre"`
+			InventoryFullyQualified string `json:"inventory_fully_qualified"`
+			ChannelID               string `json:"channel_id"`
+			ChannelType             string `json:"channel_type"`
+		} `json:"targeting_parameters"`
+		AdUnitID string `json:"ad_unit_id"`
+	} `json:"ad_placement_metadata,omitempty"`
+	SharedID             string `json:"shared_id,omitempty"`
+	HasCustomDescription bool   `json:"has_custom_description,omitempty"`
+	Thumbnails           struct {
+		Unviewed struct {
+			NeedsAuth bool   `json:"needs_auth"`
+			URL       string `json:"url"`
+		} `json:"unviewed"`
+		Viewed struct {
+			NeedsAuth bool   `json:"needs_auth"`
+			URL       string `json:"url"`
+		} `json:"viewed"`
+	} `json:"thumbnails,omitempty"`
+	IsLocal bool `json:"is_local,omitempty"`
+}
+
+// Conversation holds a Snapchat conversation as listed in Updates.ConversationsResponse.
+type Conversation struct {
+	Participants         []string      `json:"participants"`
+	LastInteractionTs    int64         `json:"last_interaction_ts"`
+	PendingChatsFor      []interface{} `json:"pending_chats_for"`
+	PendingReceivedSnaps []struct {
+		Sn                     string  `json:"sn"`
+		T                      int     `json:"t"`
+		Timer                  float64 `json:"timer"`
+		Mo                     int     `json:"mo"`
+		Broadcast              int     `json:"broadcast"`
+		BroadcastMediaURL      string  `json:"broadcast_media_url"`
+		BroadcastSecondaryText string  `json:"broadcast_secondary_text,omitempty"`
+		BroadcastHideTimer     bool    `json:"broadcast_hide_timer"`
+		EsID                   string  `json:"es_id"`
+		ID                     string  `json:"id"`
+		St                     int     `json:"st"`
+		M                      int     `json:"m"`
+		Ts                     int64   `json:"ts"`
+		Sts                    int64   `json:"sts"`
+	} `json:"pending_received_snaps"`
+	ID                   string `json:"id"`
+	ConversationMessages struct {
+		MessagingAuth struct {
+			Payload string `json:"payload"`
+			Mac     string `json:"mac"`
+			Type    string `json:"type"`
+		} `json:"messaging_auth"`
+		Messages []struct {
+			Snap struct {
+				Sn                 string  `json:"sn"`
+				T                  int     `json:"t"`
+				Timer              float64 `json:"timer"`
+				Mo                 int     `json:"mo"`
+				Broadcast          int     `json:"broadcast"`
+				BroadcastMediaURL  string  `json:"broadcast_media_url"`
+				BroadcastHideTimer bool    `json:"broadcast_hide_timer"`
+				EsID               string  `json:"es_id"`
+				ID                 string  `json:"id"`
+				St                 int     `json:"st"`
+				M                  int     `json:"m"`
+				Ts                 int64   `json:"ts"`
+				Sts                int64   `json:"sts"`
+			} `json:"snap"`
+		} `json:"messages"`
+	} `json:"conversation_messages"`
+	ConversationState struct {
+		UserSequences    map[string]int64            `json:"user_sequences"`
+		UserChatReleases map[string]map[string]int64 `json:"user_chat_releases"`
+		UserSnapReleases map[string]map[string]int64 `json:"user_snap_releases"`
+	} `json:"conversation_state"`
+	LastSnap struct {
+		Sn                 string  `json:"sn"`
+		T                  int     `json:"t"`
+		Timer              float64 `json:"timer"`
+		Mo                 int     `json:"mo"`
+		Broadcast          int     `json:"broadcast"`
+		BroadcastMediaURL  string  `json:"broadcast_media_url"`
+		BroadcastHideTimer bool    `json:"broadcast_hide_timer"`
+		EsID               string  `json:"es_id"`
+		ID                 string  `json:"id"`
+		St                 int     `json:"st"`
+		M                  int     `json:"m"`
+		Ts                 int64   `json:"ts"`
+		Sts                int64   `json:"sts"`
+	} `json:"last_snap"`
+	LastChatActions struct {
+		LastWriter         string `json:"last_writer"`
+		LastWriteTimestamp int64  `json:"last_write_timestamp"`
+		LastWriteType      string `json:"last_write_type"`
+	} `json:"last_chat_actions"`
+}
+
+// Story holds a single snap posted to a Snapchat story.
+type Story struct {
+	ID                 string  `json:"id"`
+	Username           string  `json:"username"`
+	MatureContent      bool    `json:"mature_content"`
+	ClientID           string  `json:"client_id"`
+	Timestamp          int64   `json:"timestamp"`
+	MediaID            string  `json:"media_id"`
+	MediaKey           string  `json:"media_key"`
+	MediaIv            string  `json:"media_iv"`
+	ThumbnailIv        string  `json:"thumbnail_iv"`
+	MediaType          int     `json:"media_type"`
+	Time               float64 `json:"time"`
+	CaptionTextDisplay string  `json:"caption_text_display"`
+	Zipped             bool    `json:"zipped"`
+	StoryFilterID      string  `json:"story_filter_id"`
+	Unlockables        []struct {
+		UnlockableID   string `json:"unlockable_id"`
+		UnlockableType string `json:"unlockable_type"`
+	} `json:"unlockables"`
+	TimeLeft      int    `json:"time_left"`
+	IsShared      bool   `json:"is_shared"`
+	IsFrontFacing bool   `json:"is_front_facing"`
+	IsTitleSnap   bool   `json:"is_title_snap"`
+	Orientation   int    `json:"orientation"`
+	MediaURL      string `json:"media_url"`
+	ThumbnailURL  string `json:"thumbnail_url"`
+	NeedsAuth     bool   `json:"needs_auth"`
+	AdCanFollow   bool   `json:"ad_can_follow"`
+}
+
 // StorySnap holds a single snap in a collection of stories.
 type StorySnap struct {
 	JSON struct {
-		Story struct {
-			AdCanFollow   bool    `json:"ad_can_follow"`
-			ClientID      string  `json:"client_id"`
-			ID            string  `json:"id"`
-			IsFrontFacing bool    `json:"is_front_facing"`
-			IsShared      bool    `json:"is_shared"`
-			IsTitleSnap   bool    `json:"is_title_snap"`
-			MatureContent bool    `json:"mature_content"`
-			MediaID       string  `json:"media_id"`
-			MediaIv       string  `json:"media_iv"`
-			MediaKey      string  `json:"media_key"`
-			MediaType     int     `json:"media_type"`
-			MediaURL      string  `json:"media_url"`
-			NeedsAuth     bool    `json:"needs_auth"`
-			Orientation   int     `json:"orientation"`
-			ThumbnailIv   string  `json:"thumbnail_iv"`
-			ThumbnailURL  string  `json:"thumbnail_url"`
-			Time          float64 `json:"time"`
-			TimeLeft      int     `json:"time_left"`
-			Timestamp     int64   `json:"timestamp"`
-			Username      string  `json:"username"`
-			Zipped        bool    `json:"zipped"`
-		} `json:"story"`
+		Story Story `json:"story"`
 	} `json:"json"`
 }
 
@@ -760,29 +672,8 @@ type Stories struct {
 	} `json:"mature_content_text"`
 	MyVerifiedStories []interface{} `json:"my_verified_stories"`
 	MyStories         []struct {
-		StoryNotes []interface{} `json:"story_notes"`
-		Story      struct {
-			ID                 string  `json:"id"`
-			Username           string  `json:"username"`
-			MatureContent      bool    `json:"mature_content"`
-			ClientID           string  `json:"client_id"`
-			Timestamp          int64   `json:"timestamp"`
-			MediaID            string  `json:"media_id"`
-			MediaKey           string  `json:"media_key"`
-			MediaIv            string  `json:"media_iv"`
-			ThumbnailIv        string  `json:"thumbnail_iv"`
-			MediaType          int     `json:"media_type"`
-			Time               float64 `json:"time"`
-			CaptionTextDisplay string  `json:"caption_text_display"`
-			Zipped             bool    `json:"zipped"`
-			StoryFilterID      string  `json:"story_filter_id"`
-			TimeLeft           int     `json:"time_left"`
-			IsShared           bool    `json:"is_shared"`
-			MediaURL           string  `json:"media_url"`
-			ThumbnailURL       string  `json:"thumbnail_url"`
-			NeedsAuth          bool    `json:"needs_auth"`
-			AdCanFollow        bool    `json:"ad_can_follow"`
-		} `json:"story"`
+		StoryNotes  []interface{} `json:"story_notes"`
+		Story       Story         `json:"story"`
 		StoryExtras struct {
 			ViewCount       int `json:"view_count"`
 			ScreenshotCount int `json:"screenshot_count"`
@@ -795,33 +686,8 @@ type Stories struct {
 		MatureContent      bool   `json:"mature_content"`
 		Username           string `json:"username"`
 		Stories            []struct {
-			Story struct {
-				ID                 string  `json:"id"`
-				Username           string  `json:"username"`
-				MatureContent      bool    `json:"mature_content"`
-				ClientID           string  `json:"client_id"`
-				Timestamp          int64   `json:"timestamp"`
-				MediaID            string  `json:"media_id"`
-				MediaKey           string  `json:"media_key"`
-				MediaIv            string  `json:"media_iv"`
-				ThumbnailIv        string  `json:"thumbnail_iv"`
-				MediaType          int     `json:"media_type"`
-				Time               float64 `json:"time"`
-				CaptionTextDisplay string  `json:"caption_text_display"`
-				Zipped             bool    `json:"zipped"`
-				StoryFilterID      string  `json:"story_filter_id"`
-				Unlockables        []struct {
-					UnlockableID   string `json:"unlockable_id"`
-					UnlockableType string `json:"unlockable_type"`
-				} `json:"unlockables"`
-				TimeLeft     int    `json:"time_left"`
-				IsShared     bool   `json:"is_shared"`
-				MediaURL     string `json:"media_url"`
-				ThumbnailURL string `json:"thumbnail_url"`
-				NeedsAuth    bool   `json:"needs_auth"`
-				AdCanFollow  bool   `json:"ad_can_follow"`
-			} `json:"story"`
-			Viewed bool `json:"viewed"`
+			Story  Story `json:"story"`
+			Viewed bool  `json:"viewed"`
 		} `json:"stories"`
 		AdPlacementMetadata struct {
 			AdInsertionConfig struct {
@@ -1094,6 +960,9 @@ type LensSchedule struct {
 			} `json:"lens_data"`
 		} `json:"2015-10-21T00:00-0700"`
 	} `json:"schedule"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
 }
 
 // Friend holds data about a Snapchat friend action.
@@ -1115,15 +984,90 @@ type Friend struct {
 		SnapStreakCount     int      `json:"snap_streak_count"`
 	} `json:"object"`
 	Logged bool `json:"logged"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
 }
 
 // SnapTag holds Snaptag data about a single Snaptag. Could be either a base64'd PNG or SVG image.
 type SnapTag struct {
 	Imagedata string `json:"imageData"`
 	Qrpath    string `json:"qrPath"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
 }
 
 // SuggestedFriends holds results of suggested friends Snapchat recommends to you.
 type SuggestedFriends struct {
 	SuggestedFriendResults []interface{} `json:"suggested_friend_results"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// PhoneVerification holds the response to a phone number verification or an SMS code sent to Snapchat.
+type PhoneVerification struct {
+	Logged  bool   `json:"logged"`
+	Message string `json:"message"`
+	Param   string `json:"param"`
+	Status  int    `json:"status"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// IPRouting holds the URLs Snapchat routes requests of the authenticated user to.
+type IPRouting struct {
+	URLEntities map[string]interface{} `json:"urlEntities"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// UserExists holds whether a username exists in Snapchat.
+type UserExists struct {
+	Exists bool `json:"exists"`
+	Logged bool `json:"logged"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// FindFriends holds the Snapchat users found from a list of contacts.
+type FindFriends struct {
+	Logged  bool `json:"logged"`
+	Results []struct {
+		Name    string `json:"name"`
+		Display string `json:"display"`
+		Type    int    `json:"type"`
+	} `json:"results"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// BestFriends holds the best friends and score of Snapchat users, keyed by username.
+type BestFriends struct {
+	Friends map[string]struct {
+		BestFriends []string `json:"best_friends"`
+		Score       int      `json:"score"`
+	}
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// Sent holds the response to media sent to other Snapchat users.
+type Sent struct {
+	SnapResponse struct {
+		Success bool `json:"success"`
+		Snaps   map[string]struct {
+			ID        string `json:"id"`
+			Timestamp int64  `json:"timestamp"`
+		} `json:"snaps"`
+	} `json:"snap_response"`
+
+	// Raw is the response body the model was decoded from.
+	Raw []byte `json:"-"`
 }
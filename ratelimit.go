@@ -0,0 +1,122 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting how often requests are made.
+// It is safe for concurrent use, so one RateLimiter can be shared by every
+// client using the same Casper API key or the same Snapchat account.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	blocked time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate requests per second on average
+// and bursts of up to burst requests.
+// A rate of 0 or less does not limit requests, the RateLimiter then only honours Delay.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+// A nil RateLimiter allows every request.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		d := l.reserve()
+		if d <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Delay blocks every request for d, usually the Retry-After time sent by a server.
+func (l *RateLimiter) Delay(d time.Duration) {
+	if l == nil || d <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.blocked) {
+		l.blocked = until
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before trying again.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.blocked) {
+		return l.blocked.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// parseRetryAfter returns the wait asked for by the Retry-After header in h,
+// given either in seconds or as a HTTP date.
+func parseRetryAfter(h http.Header) time.Duration {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// retryAfter returns the Retry-After wait carried by err, if any.
+func retryAfter(err error) time.Duration {
+	var scErr SnapchatError
+	if errors.As(err, &scErr) {
+		return scErr.RetryAfter
+	}
+	var apiErr APIErrorResponseModel
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package casper
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test RateLimiter.
+func TestRateLimiter(t *testing.T) {
+	var paramTests = []struct {
+		rate     float64
+		burst    int
+		requests int
+		minWait  time.Duration
+	}{
+		{1000, 5, 5, 0},
+		{100, 1, 3, 15 * time.Millisecond},
+		{50, 2, 4, 30 * time.Millisecond},
+		{0, 1, 5, 0},
+		{-1, 1, 5, 0},
+	}
+
+	for _, test := range paramTests {
+		limiter := NewRateLimiter(test.rate, test.burst)
+		start := time.Now()
+		for i := 0; i < test.requests; i++ {
+			if err := limiter.Wait(context.Background()); err != nil {
+				t.Fatalf("Wait() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "<nil>", err)
+			}
+		}
+		waited := time.Since(start)
+		if test.minWait == 0 && waited > time.Second {
+			t.Errorf("NewRateLimiter(%v, %d) failed test. \n\n\rWant: \n\r\"< %s\" \n\rGot: \n\r\"%s\" \n\n", test.rate, test.burst, time.Second, waited)
+		}
+		if waited < test.minWait {
+			t.Errorf("NewRateLimiter(%v, %d) failed test. \n\n\rWant: \n\r\">= %s\" \n\rGot: \n\r\"%s\" \n\n", test.rate, test.burst, test.minWait, waited)
+		}
+	}
+}
+
+// Test RateLimiterDelay.
+func TestRateLimiterDelay(t *testing.T) {
+	limiter := NewRateLimiter(1000, 10)
+	limiter.Delay(20 * time.Millisecond)
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("Delay(%s) failed test. \n\n\rWant: \n\r\">= %s\" \n\rGot: \n\r\"%s\" \n\n", 20*time.Millisecond, 20*time.Millisecond, waited)
+	}
+
+	limiter.Delay(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Errorf("Wait() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", context.DeadlineExceeded, "<nil>")
+	}
+
+	var nilLimiter *RateLimiter
+	if err := nilLimiter.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() on a nil RateLimiter failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "<nil>", err)
+	}
+}
+
+// Test ParseRetryAfter.
+func TestParseRetryAfter(t *testing.T) {
+	var paramTests = []struct {
+		value       string
+		expectation time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"soon", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", time.Until(time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC))},
+	}
+
+	for _, test := range paramTests {
+		header := http.Header{}
+		header.Set("Retry-After", test.value)
+		result := parseRetryAfter(header)
+		if diff := result - test.expectation; diff > time.Second || diff < -time.Second {
+			t.Errorf("parseRetryAfter(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.value, test.expectation, result)
+		}
+	}
+}
+
+// Test RetryAfterResponse.
+func TestRetryAfterResponse(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Retry-After", "1")
+		rw.WriteHeader(http.StatusTooManyRequests)
+	})
+	defer closeServers()
+	testCasperClient.SnapchatLimiter = NewRateLimiter(1000, 10)
+
+	_, err := testCasperClient.Call(context.Background(), "/loq/all_updates", nil)
+	if retryAfter(err) != time.Second {
+		t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/loq/all_updates", time.Second, retryAfter(err))
+	}
+	if d := testCasperClient.SnapchatLimiter.reserve(); d <= 0 {
+		t.Errorf("SnapchatLimiter failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "a delay", d)
+	}
+}
@@ -0,0 +1,59 @@
+package casper
+
+import (
+	"context"
+	"errors"
+)
+
+// CredentialProvider supplies the username and password used to log in again when AutoLogin is on,
+// so they do not have to be kept in Casper.Password.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (username string, password string, err error)
+}
+
+// CredentialsFunc adapts a function to a CredentialProvider.
+type CredentialsFunc func(ctx context.Context) (username string, password string, err error)
+
+// Credentials calls f(ctx).
+func (f CredentialsFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// relogin calls fn and, when c.AutoLogin is on and fn fails because the auth token expired,
+// logs in again once and replays fn.
+// Requests that fail together only log in once, the others replay with the new auth token.
+func (c *Casper) relogin(ctx context.Context, fn func() error) error {
+	_, authToken := c.account()
+	err := fn()
+	if !c.AutoLogin || !errors.Is(err, ErrAuthExpired) {
+		return err
+	}
+	if err := c.loginAgain(ctx, authToken); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// loginAgain logs in again unless the auth token already changed from staleAuthToken.
+func (c *Casper) loginAgain(ctx context.Context, staleAuthToken string) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	c.authMu.RLock()
+	username, password, authToken := c.Username, c.Password, c.AuthToken
+	c.authMu.RUnlock()
+	if authToken != staleAuthToken {
+		return nil
+	}
+	if c.Credentials != nil {
+		var err error
+		username, password, err = c.Credentials.Credentials(ctx)
+		if err != nil {
+			return Error{Err: casperAuthError, Reason: err}
+		}
+	}
+	if username == "" || password == "" {
+		return Error{Err: casperAuthError, Reason: errors.New("no credentials to log in again")}
+	}
+	_, err := c.authenticate(ctx, username, password)
+	return err
+}
@@ -0,0 +1,170 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Test AutoLogin.
+func TestAutoLogin(t *testing.T) {
+	var paramTests = []struct {
+		autoLogin   bool
+		password    string
+		credentials CredentialProvider
+		expectation error
+		logins      int32
+	}{
+		{false, "test_api_password", nil, ErrAuthExpired, 0},
+		{true, "test_api_password", nil, nil, 1},
+		{true, "", CredentialsFunc(func(ctx context.Context) (string, string, error) {
+			return "test_api_user", "test_api_password", nil
+		}), nil, 1},
+		{true, "", CredentialsFunc(func(ctx context.Context) (string, string, error) {
+			return "", "", errors.New("no credentials")
+		}), ErrAuth, 0},
+	}
+
+	for _, test := range paramTests {
+		var logins int32
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == "/loq/login":
+				atomic.AddInt32(&logins, 1)
+				rw.Write([]byte(`{"updates_response": {"auth_token": "new_auth_token"}}`))
+			case req.Header.Get("X-Snapchat-Client-Auth-Token") != "new_auth_token":
+				rw.WriteHeader(http.StatusUnauthorized)
+			default:
+				rw.Write([]byte(`{}`))
+			}
+		})
+		casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			claims := testClaims(req)
+			fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"endpoint": "%s", "headers": {"X-Snapchat-Client-Auth-Token": "%s"}}]}`, claims["endpoint"], claims["auth_token"])
+		}))
+		testCasperClient.CasperURL = casperServer.URL
+		testCasperClient.Password = test.password
+		testCasperClient.AutoLogin = test.autoLogin
+		testCasperClient.Credentials = test.credentials
+
+		_, err := testCasperClient.Call(context.Background(), "/loq/all_updates", nil)
+		if !errors.Is(err, test.expectation) {
+			t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "/loq/all_updates", test.expectation, err)
+		}
+		if result := atomic.LoadInt32(&logins); result != test.logins {
+			t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", "/loq/all_updates", test.logins, result)
+		}
+		if test.expectation == nil && testCasperClient.AuthToken != "new_auth_token" {
+			t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/loq/all_updates", "new_auth_token", testCasperClient.AuthToken)
+		}
+		if testCasperClient.Password != test.password {
+			t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/loq/all_updates", test.password, testCasperClient.Password)
+		}
+		casperServer.Close()
+		closeServers()
+	}
+}
+
+// Test AutoLogin with concurrent requests.
+func TestAutoLoginConcurrent(t *testing.T) {
+	const requests = 4
+
+	var logins int32
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/loq/login":
+			atomic.AddInt32(&logins, 1)
+			rw.Write([]byte(`{"updates_response": {"auth_token": "new_auth_token"}}`))
+		case req.Header.Get("X-Snapchat-Client-Auth-Token") != "new_auth_token":
+			rw.WriteHeader(http.StatusUnauthorized)
+		default:
+			rw.Write([]byte(`{}`))
+		}
+	})
+	defer closeServers()
+	casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		claims := testClaims(req)
+		fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"endpoint": "%s", "headers": {"X-Snapchat-Client-Auth-Token": "%s"}}]}`, claims["endpoint"], claims["auth_token"])
+	}))
+	defer casperServer.Close()
+	testCasperClient.CasperURL = casperServer.URL
+	testCasperClient.Password = "test_api_password"
+	testCasperClient.AutoLogin = true
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := testCasperClient.Call(context.Background(), "/loq/all_updates", nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "/loq/all_updates", nil, err)
+		}
+	}
+	if result := atomic.LoadInt32(&logins); result != 1 {
+		t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%d\" \n\rGot: \n\r\"%d\" \n\n", "/loq/all_updates", 1, result)
+	}
+}
+
+// Test the account saved by Login and AutoLogin.
+func TestLoginAccount(t *testing.T) {
+	var paramTests = []struct {
+		login       bool
+		credentials CredentialProvider
+		username    string
+		password    string
+	}{
+		{true, nil, "test_other_user", "test_other_password"},
+		{false, CredentialsFunc(func(ctx context.Context) (string, string, error) {
+			return "test_other_user", "test_other_password", nil
+		}), "test_other_user", ""},
+	}
+
+	for _, test := range paramTests {
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == "/loq/login":
+				rw.Write([]byte(`{"updates_response": {"auth_token": "` + req.FormValue("username") + `_auth_token"}}`))
+			case req.Header.Get("X-Snapchat-Client-Auth-Token") != "test_other_user_auth_token":
+				rw.WriteHeader(http.StatusUnauthorized)
+			default:
+				rw.Write([]byte(`{}`))
+			}
+		})
+		casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			claims := testClaims(req)
+			fmt.Fprintf(rw, `{"code": 200, "endpoints": [{"endpoint": "%s", "headers": {"X-Snapchat-Client-Auth-Token": "%s"}}]}`, claims["endpoint"], claims["auth_token"])
+		}))
+		testCasperClient.CasperURL = casperServer.URL
+		testCasperClient.AutoLogin = true
+		testCasperClient.Credentials = test.credentials
+
+		var err error
+		if test.login {
+			_, err = testCasperClient.Login("test_other_user", "test_other_password")
+		} else {
+			_, err = testCasperClient.Call(context.Background(), "/loq/all_updates", nil)
+		}
+		if err != nil {
+			t.Errorf("Login(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", test.username, nil, err)
+		}
+		result := testCasperClient.Username + " " + testCasperClient.AuthToken + " " + testCasperClient.Password
+		if expectation := test.username + " " + test.username + "_auth_token " + test.password; result != expectation {
+			t.Errorf("Login(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.username, expectation, result)
+		}
+		casperServer.Close()
+		closeServers()
+	}
+}
@@ -0,0 +1,118 @@
+package casper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how a Casper client retries failed requests.
+//
+// MaxAttempts is the total number of attempts, including the first one.
+// The wait before the nth retry is MinBackoff doubled n-1 times, capped at MaxBackoff,
+// of which a random half is jittered away so many clients do not retry in lockstep.
+// Retryable decides which errors are retried and defaults to IsRetryable.
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	Retryable   func(err error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy making up to 4 attempts, waiting between 500ms and 10s.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+	}
+}
+
+// IsRetryable reports whether err is transient: a timeout, a connection that failed to dial,
+// was reset or closed early, a rate limited request or a 5xx response from the Casper API or from Snapchat.
+// Other network errors, such as a bad certificate or an unsupported URL scheme, are not retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var scErr SnapchatError
+	if errors.As(err, &scErr) {
+		return scErr.StatusCode >= http.StatusInternalServerError
+	}
+	var apiErr APIErrorResponseModel
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= http.StatusInternalServerError
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write") {
+		return true
+	}
+	// Every *url.Error is a net.Error, so only the timeout it reports for its cause counts.
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff returns how long to wait before retry number attempt, starting at 1.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryable reports whether err should be retried under p.
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// retry calls fn until it succeeds, fails with an error that is not retryable,
+// runs out of attempts under c.Retry or ctx is done.
+// It waits at least as long as a Retry-After header asked for between attempts.
+func (c *Casper) retry(ctx context.Context, fn func() error) error {
+	p := c.Retry
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || p == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+		if c.Debug == true {
+			fmt.Printf("RETRY %d\t%s\n", attempt, err)
+		}
+		wait := p.backoff(attempt)
+		if d := retryAfter(err); d > wait {
+			wait = d
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("casper: %w after %d attempts, last error: %v", ctx.Err(), attempt, err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package casper
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test Retry.
+func TestRetry(t *testing.T) {
+	var paramTests = []struct {
+		failures        int
+		status          int
+		maxAttempts     int
+		expectedCalls   int
+		expectedSuccess bool
+	}{
+		{0, http.StatusServiceUnavailable, 3, 1, true},
+		{2, http.StatusServiceUnavailable, 3, 3, true},
+		{2, http.StatusTooManyRequests, 3, 3, true},
+		{3, http.StatusBadGateway, 3, 3, false},
+		{2, http.StatusBadRequest, 3, 1, false},
+	}
+
+	for _, test := range paramTests {
+		var reqTokens []string
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			reqTokens = append(reqTokens, req.FormValue("req_token"))
+			if len(reqTokens) <= test.failures {
+				rw.WriteHeader(test.status)
+			}
+		})
+		casperCalls := 0
+		casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			casperCalls++
+			writeTestEndpointAuth(rw, testClaims(req), "test_req_token_"+strconv.Itoa(casperCalls))
+		}))
+		testCasperClient.CasperURL = casperServer.URL
+		testCasperClient.Retry = &RetryPolicy{
+			MaxAttempts: test.maxAttempts,
+			MinBackoff:  time.Millisecond,
+			MaxBackoff:  2 * time.Millisecond,
+		}
+
+		_, err := testCasperClient.Call(context.Background(), "/loq/all_updates", nil)
+		if (err == nil) != test.expectedSuccess {
+			t.Errorf("Call(%q) with %d failures of %d failed test. \n\n\rWant: \n\r\"%t\" \n\rGot: \n\r\"%v\" \n\n", "/loq/all_updates", test.failures, test.status, test.expectedSuccess, err)
+		}
+		if len(reqTokens) != test.expectedCalls || casperCalls != test.expectedCalls {
+			t.Errorf("Call(%q) with %d failures of %d failed test. \n\n\rWant: \n\r\"%d\" calls \n\rGot: \n\r\"%d\" Snapchat and \"%d\" Casper calls \n\n", "/loq/all_updates", test.failures, test.status, test.expectedCalls, len(reqTokens), casperCalls)
+		}
+		for i, reqToken := range reqTokens {
+			if expected := "test_req_token_" + strconv.Itoa(i+1); reqToken != expected {
+				t.Errorf("Call(%q) attempt %d failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "/loq/all_updates", i+1, expected, reqToken)
+			}
+		}
+		casperServer.Close()
+		closeServers()
+	}
+}
+
+// Test IsRetryable.
+func TestIsRetryable(t *testing.T) {
+	var paramTests = []struct {
+		err         error
+		expectation bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{Error{Err: casperHTTPError, Reason: context.DeadlineExceeded}, false},
+		{SnapchatError{StatusCode: 503}, true},
+		{SnapchatError{StatusCode: 429}, true},
+		{SnapchatError{StatusCode: 401}, false},
+		{apiError(APIErrorResponseModel{Code: 502}), true},
+		{apiError(APIErrorResponseModel{Code: 400, Message: "JWT Exception: Signature verification failed"}), false},
+		{Error{Err: casperHTTPError, Reason: &timeoutError{}}, true},
+		{Error{Err: casperHTTPError, Reason: &url.Error{Op: "Post", URL: "https://app.snapchat.com", Err: &timeoutError{}}}, true},
+		{Error{Err: casperHTTPError, Reason: &url.Error{Op: "Post", URL: "https://app.snapchat.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}}, true},
+		{Error{Err: casperHTTPError, Reason: &url.Error{Op: "Post", URL: "https://app.snapchat.com", Err: io.ErrUnexpectedEOF}}, true},
+		{Error{Err: casperHTTPError, Reason: &url.Error{Op: "Post", URL: "https://app.snapchat.com", Err: x509.UnknownAuthorityError{}}}, false},
+		{Error{Err: casperHTTPError, Reason: &url.Error{Op: "Post", URL: "ftp://app.snapchat.com", Err: errors.New(`unsupported protocol scheme "ftp"`)}}, false},
+		{errors.New("unknown"), false},
+	}
+
+	for _, test := range paramTests {
+		result := IsRetryable(test.err)
+		if result != test.expectation {
+			t.Errorf("IsRetryable(%v) failed test. \n\n\rWant: \n\r\"%t\" \n\rGot: \n\r\"%t\" \n\n", test.err, test.expectation, result)
+		}
+	}
+}
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+// Test Retry when ctx ends during the backoff.
+func TestRetryDeadline(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer closeServers()
+	testCasperClient.Retry = &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Second,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := testCasperClient.Call(ctx, "/loq/all_updates", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "/loq/all_updates", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,191 @@
+package casper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SessionVersion is the version of the session format written by MarshalSession.
+const SessionVersion = 1
+
+// Parameters of the key derivation used to encrypt sessions with a passphrase.
+const (
+	sessionKDF        = "pbkdf2-sha256"
+	sessionIterations = 100000
+	sessionSaltSize   = 16
+)
+
+// device holds the identifiers of the device an account logged in with.
+// They are sent again whenever the account logs in, the Casper API signs every other request with its own.
+type device struct {
+	UUID        string `json:"x_snapchat_uuid,omitempty"`
+	ClientToken string `json:"x_snapchat_client_token,omitempty"`
+}
+
+// sessionFile is the versioned JSON document written by MarshalSession.
+// It holds either the session in the clear or, when a passphrase was given,
+// the session encrypted with AES-256-GCM under a key derived from the passphrase.
+type sessionFile struct {
+	Version    int            `json:"version"`
+	Session    *session       `json:"session,omitempty"`
+	Encryption *sessionCipher `json:"encryption,omitempty"`
+	Data       []byte         `json:"data,omitempty"`
+}
+
+// session is everything needed to use a logged in account again.
+type session struct {
+	Username  string `json:"username"`
+	AuthToken string `json:"auth_token"`
+	Device    device `json:"device"`
+	SavedAt   int64  `json:"saved_at"`
+}
+
+// sessionCipher describes how the data of an encrypted sessionFile was encrypted.
+type sessionCipher struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+}
+
+// MarshalSession returns the session of a logged in client as versioned JSON, so it can be restored
+// with RestoreSession after a restart without logging in again.
+// The device identifiers the account logged in with are saved as well, so logging in again after
+// a restore, for example with AutoLogin, does not show up as a new device.
+// When passphrase is not empty the session is encrypted with it. The password is never saved.
+func (c *Casper) MarshalSession(passphrase string) ([]byte, error) {
+	err := c.checkToken()
+	if err != nil {
+		return nil, err
+	}
+	c.authMu.RLock()
+	s := session{
+		Username:  c.Username,
+		AuthToken: c.AuthToken,
+		Device:    c.device,
+		SavedAt:   time.Now().Unix(),
+	}
+	c.authMu.RUnlock()
+	file := sessionFile{
+		Version: SessionVersion,
+	}
+	if passphrase == "" {
+		file.Session = &s
+		return json.Marshal(file)
+	}
+
+	plaintext, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, sessionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := sessionAEAD(passphrase, salt, sessionIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	file.Encryption = &sessionCipher{
+		KDF:        sessionKDF,
+		Iterations: sessionIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+	}
+	file.Data = aead.Seal(nil, nonce, plaintext, nil)
+	return json.Marshal(file)
+}
+
+// RestoreSession restores a session written by MarshalSession, decrypting it with passphrase if it was encrypted.
+// A session that cannot be restored, for example because of a wrong passphrase, fails with ErrSession.
+func (c *Casper) RestoreSession(data []byte, passphrase string) error {
+	var file sessionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Error{Err: casperSessionError, Reason: err}
+	}
+	if file.Version != SessionVersion {
+		return Error{Err: casperSessionError, Reason: fmt.Errorf("unsupported session version %d", file.Version)}
+	}
+
+	s := file.Session
+	if file.Encryption != nil {
+		if passphrase == "" {
+			return Error{Err: casperSessionError, Reason: errors.New("session is encrypted, a passphrase is needed")}
+		}
+		if file.Encryption.KDF != sessionKDF || file.Encryption.Iterations < 1 {
+			return Error{Err: casperSessionError, Reason: fmt.Errorf("unsupported session encryption %s", file.Encryption.KDF)}
+		}
+		aead, err := sessionAEAD(passphrase, file.Encryption.Salt, file.Encryption.Iterations)
+		if err != nil {
+			return Error{Err: casperSessionError, Reason: err}
+		}
+		if len(file.Encryption.Nonce) != aead.NonceSize() {
+			return Error{Err: casperSessionError, Reason: errors.New("invalid session nonce")}
+		}
+		plaintext, err := aead.Open(nil, file.Encryption.Nonce, file.Data, nil)
+		if err != nil {
+			return Error{Err: casperSessionError, Reason: errors.New("wrong passphrase or corrupted session")}
+		}
+		s = new(session)
+		if err := json.Unmarshal(plaintext, s); err != nil {
+			return Error{Err: casperSessionError, Reason: err}
+		}
+	}
+	if s == nil || s.Username == "" || s.AuthToken == "" {
+		return Error{Err: casperSessionError, Reason: errors.New("session has no username or auth token")}
+	}
+
+	c.authMu.Lock()
+	c.Username = s.Username
+	c.AuthToken = s.AuthToken
+	c.device = s.Device
+	c.authMu.Unlock()
+	c.ExpireEndpointCache()
+	return nil
+}
+
+// sessionAEAD returns AES-256-GCM keyed with passphrase stretched by PBKDF2 over salt.
+func sessionAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), salt, iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key of keyLen bytes from password and salt with PBKDF2 using HMAC-SHA256, as in RFC 8018.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		var counter [4]byte
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
@@ -0,0 +1,134 @@
+package casper
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test Session.
+func TestSession(t *testing.T) {
+	var paramTests = []struct {
+		passphrase        string
+		restorePassphrase string
+		expectation       error
+	}{
+		{"", "", nil},
+		{"test_passphrase", "test_passphrase", nil},
+		{"test_passphrase", "wrong_passphrase", ErrSession},
+		{"test_passphrase", "", ErrSession},
+	}
+
+	for _, test := range paramTests {
+		testCasperClient := &Casper{
+			Username:  "test_api_user",
+			AuthToken: "test_auth_token",
+			device: device{
+				UUID: "test_uuid",
+			},
+		}
+		data, err := testCasperClient.MarshalSession(test.passphrase)
+		if err != nil {
+			t.Fatalf("MarshalSession(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.passphrase, "<nil>", err)
+		}
+
+		restored := &Casper{}
+		err = restored.RestoreSession(data, test.restorePassphrase)
+		if !errors.Is(err, test.expectation) {
+			t.Errorf("RestoreSession(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", test.restorePassphrase, test.expectation, err)
+		}
+		if err != nil {
+			continue
+		}
+		if restored.Username != "test_api_user" || restored.AuthToken != "test_auth_token" || restored.device.UUID != "test_uuid" {
+			t.Errorf("RestoreSession(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.restorePassphrase, "test_api_user test_auth_token test_uuid", restored.Username+" "+restored.AuthToken+" "+restored.device.UUID)
+		}
+	}
+}
+
+// Test RestoreSession with invalid sessions.
+func TestRestoreInvalidSession(t *testing.T) {
+	var paramTests = []struct {
+		data string
+	}{
+		{`not json`},
+		{`{"version": 2, "session": {"username": "test_api_user", "auth_token": "test_auth_token"}}`},
+		{`{"version": 1}`},
+		{`{"version": 1, "session": {"username": "test_api_user"}}`},
+	}
+
+	for _, test := range paramTests {
+		err := (&Casper{}).RestoreSession([]byte(test.data), "")
+		if !errors.Is(err, ErrSession) {
+			t.Errorf("RestoreSession(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", test.data, ErrSession, err)
+		}
+	}
+}
+
+// Test PBKDF2SHA256 against the test vectors of RFC 7914.
+func TestPBKDF2SHA256(t *testing.T) {
+	var paramTests = []struct {
+		password    string
+		salt        string
+		iterations  int
+		expectation string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+
+	for _, test := range paramTests {
+		result := hex.EncodeToString(pbkdf2SHA256([]byte(test.password), []byte(test.salt), test.iterations, 64))
+		if result != test.expectation {
+			t.Errorf("pbkdf2SHA256(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.password, test.expectation, result)
+		}
+	}
+}
+
+// Test logging in again with the device of a restored session.
+func TestSessionDevice(t *testing.T) {
+	var uuids []string
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		uuids = append(uuids, req.Header.Get("X-Snapchat-UUID"))
+		rw.Write([]byte(`{"updates_response": {"auth_token": "new_auth_token"}}`))
+	})
+	defer closeServers()
+	casperServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"code": 200, "headers": {"X-Snapchat-UUID": "test_uuid"}}`))
+	}))
+	defer casperServer.Close()
+	testCasperClient.CasperURL = casperServer.URL
+	testCasperClient.device = device{UUID: "saved_uuid"}
+	data, err := testCasperClient.MarshalSession("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := &Casper{
+		APIKey:      testCasperClient.APIKey,
+		APISecret:   testCasperClient.APISecret,
+		CasperURL:   testCasperClient.CasperURL,
+		SnapchatURL: testCasperClient.SnapchatURL,
+	}
+	if err := restored.RestoreSession(data, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restored.Login("test_api_user", "test_api_password"); err != nil {
+		t.Fatal(err)
+	}
+	fresh := &Casper{
+		APIKey:      testCasperClient.APIKey,
+		APISecret:   testCasperClient.APISecret,
+		CasperURL:   testCasperClient.CasperURL,
+		SnapchatURL: testCasperClient.SnapchatURL,
+	}
+	if _, err := fresh.Login("test_api_user", "test_api_password"); err != nil {
+		t.Fatal(err)
+	}
+	if result := fmt.Sprint(uuids); result != "[saved_uuid test_uuid]" || fresh.device.UUID != "test_uuid" {
+		t.Errorf("Login(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "test_api_user", "[saved_uuid test_uuid]", result)
+	}
+}
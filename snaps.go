@@ -0,0 +1,90 @@
+package casper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hako/casper/media"
+)
+
+// DownloadSnap downloads the received snap id, decrypts it and writes the media to w.
+// Snap IDs can be found in the PendingReceivedSnaps of an Updates model.
+func (c *Casper) DownloadSnap(id string, w io.Writer) error {
+	return c.DownloadSnapContext(context.Background(), id, w)
+}
+
+// DownloadSnapContext is like DownloadSnap but carries ctx to every request it makes.
+func (c *Casper) DownloadSnapContext(ctx context.Context, id string, w io.Writer) error {
+	res, err := c.open(ctx, "/bq/blob", map[string]string{
+		"id": id,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decryptSnap(w, res.Body)
+}
+
+// MarkSnapViewed reports the received snap id as viewed at viewedAt, and as screenshotted if screenshot is true.
+func (c *Casper) MarkSnapViewed(id string, viewedAt time.Time, screenshot bool) error {
+	return c.MarkSnapViewedContext(context.Background(), id, viewedAt, screenshot)
+}
+
+// MarkSnapViewedContext is like MarkSnapViewed but carries ctx to every request it makes.
+func (c *Casper) MarkSnapViewedContext(ctx context.Context, id string, viewedAt time.Time, screenshot bool) error {
+	ts := viewedAt.Unix()
+	state := 0
+	events := []snapEvent{
+		{EventName: "SNAP_VIEW", Params: map[string]string{"id": id}, Ts: ts},
+	}
+	if screenshot {
+		state = 1
+		events = append(events, snapEvent{EventName: "SNAP_SCREENSHOT", Params: map[string]string{"id": id}, Ts: ts})
+	}
+	snapInfo, err := json.Marshal(map[string]map[string]int64{
+		id: {"t": ts, "c": int64(state), "replayed": 0},
+	})
+	if err != nil {
+		return err
+	}
+	snapEvents, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	_, err = c.Call(ctx, "/bq/update_snaps", map[string]string{
+		"added_friends_timestamp": strconv.FormatInt(ts, 10),
+		"json":                    string(snapInfo),
+		"events":                  string(snapEvents),
+	})
+	return err
+}
+
+// snapEvent is an event reported to Snapchat when updating snaps.
+type snapEvent struct {
+	EventName string            `json:"eventName"`
+	Params    map[string]string `json:"params"`
+	Ts        int64             `json:"ts"`
+}
+
+// decryptSnap copies the snap read from r to w, decrypting it unless Snapchat sent it unencrypted.
+func decryptSnap(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(8)
+	if isMedia(magic) {
+		_, err := io.Copy(w, br)
+		return err
+	}
+	return media.DecryptSnap(w, br)
+}
+
+// isMedia reports whether magic starts like an unencrypted JPEG, MP4 or zip file.
+func isMedia(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte{0xff, 0xd8}) ||
+		bytes.HasPrefix(magic, []byte("PK\x03\x04")) ||
+		len(magic) >= 8 && bytes.Equal(magic[4:8], []byte("ftyp"))
+}
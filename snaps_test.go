@@ -0,0 +1,113 @@
+package casper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hako/casper/media"
+)
+
+// Test DownloadSnap.
+func TestDownloadSnap(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0test_jpeg_data")
+	var encrypted bytes.Buffer
+	if err := media.EncryptSnap(&encrypted, bytes.NewReader(jpeg)); err != nil {
+		t.Fatal(err)
+	}
+
+	var paramTests = []struct {
+		blob        []byte
+		expectation error
+	}{
+		{encrypted.Bytes(), nil},
+		{jpeg, nil},
+		{[]byte(`{"logged": false, "message": "Snap not found", "status": -100}`), ErrSnapchat},
+	}
+
+	for _, test := range paramTests {
+		blob := test.blob
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/bq/blob" || req.FormValue("id") != "test_snap_id" {
+				t.Errorf("DownloadSnap(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "test_snap_id", "/bq/blob?id=test_snap_id", req.URL.Path+"?id="+req.FormValue("id"))
+			}
+			rw.Write(blob)
+		})
+
+		var downloaded bytes.Buffer
+		err := testCasperClient.DownloadSnap("test_snap_id", &downloaded)
+		if !errors.Is(err, test.expectation) {
+			t.Fatalf("DownloadSnap(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", "test_snap_id", test.expectation, err)
+		}
+		if test.expectation == nil && !bytes.Equal(downloaded.Bytes(), jpeg) {
+			t.Errorf("DownloadSnap(%q) failed test. \n\n\rWant: \n\r\"%x\" \n\rGot: \n\r\"%x\" \n\n", "test_snap_id", jpeg, downloaded.Bytes())
+		}
+		closeServers()
+	}
+}
+
+// Test MarkSnapViewed.
+func TestMarkSnapViewed(t *testing.T) {
+	var paramTests = []struct {
+		screenshot  bool
+		state       int64
+		eventsCount int
+	}{
+		{false, 0, 1},
+		{true, 1, 2},
+	}
+
+	viewedAt := time.Unix(1457484764, 0)
+	for _, test := range paramTests {
+		var snapInfo map[string]map[string]int64
+		var events []snapEvent
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			json.Unmarshal([]byte(req.FormValue("json")), &snapInfo)
+			json.Unmarshal([]byte(req.FormValue("events")), &events)
+		})
+
+		if err := testCasperClient.MarkSnapViewed("test_snap_id", viewedAt, test.screenshot); err != nil {
+			t.Fatalf("MarkSnapViewed(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "test_snap_id", "<nil>", err)
+		}
+		info := snapInfo["test_snap_id"]
+		if info["t"] != viewedAt.Unix() || info["c"] != test.state || len(events) != test.eventsCount {
+			t.Errorf("MarkSnapViewed(%q, %t) failed test. \n\n\rWant: \n\r\"t=%d c=%d events=%d\" \n\rGot: \n\r\"t=%d c=%d events=%d\" \n\n", "test_snap_id", test.screenshot, viewedAt.Unix(), test.state, test.eventsCount, info["t"], info["c"], len(events))
+		}
+		closeServers()
+	}
+}
+
+// Test SendZipped.
+func TestSendZipped(t *testing.T) {
+	var paramTests = []struct {
+		zipped      bool
+		expectation string
+	}{
+		{false, "0"},
+		{true, "1"},
+	}
+
+	for _, test := range paramTests {
+		var zipped string
+		testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+			zipped = req.FormValue("zipped")
+		})
+
+		var err error
+		if test.zipped {
+			_, err = testCasperClient.SendZipped("TEST_API_USER~media", []string{"test_friend"}, 10)
+		} else {
+			_, err = testCasperClient.Send("TEST_API_USER~media", []string{"test_friend"}, 10)
+		}
+		if err != nil {
+			t.Fatalf("SendZipped() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", "<nil>", err)
+		}
+		if zipped != test.expectation {
+			t.Errorf("SendZipped() failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", test.expectation, zipped)
+		}
+		closeServers()
+	}
+}
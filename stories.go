@@ -0,0 +1,253 @@
+package casper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hako/casper/media"
+)
+
+// StoryArchive holds the metadata written as a JSON sidecar next to every story archived by ArchiveFriendStories.
+type StoryArchive struct {
+	Username      string `json:"username"`
+	DisplayName   string `json:"display_name"`
+	Viewed        bool   `json:"viewed"`
+	ArchivedAt    int64  `json:"archived_at"`
+	MediaFile     string `json:"media_file"`
+	ThumbnailFile string `json:"thumbnail_file,omitempty"`
+	Story         Story  `json:"story"`
+}
+
+// StoryErrors maps the IDs of stories to the errors ArchiveFriendStories got archiving them.
+type StoryErrors map[string]error
+
+// Error lists every story that failed to archive and its error, sorted by story ID.
+func (e StoryErrors) Error() string {
+	var failures []string
+	for _, id := range e.ids() {
+		failures = append(failures, id+": "+e[id].Error())
+	}
+	return "casper: " + strings.Join(failures, "; ")
+}
+
+// Is reports whether the error of any failed story matches target.
+func (e StoryErrors) Is(target error) bool {
+	for _, id := range e.ids() {
+		if errors.Is(e[id], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of a failed story, sorted by story ID, that matches target and sets target to it.
+func (e StoryErrors) As(target interface{}) bool {
+	for _, id := range e.ids() {
+		if errors.As(e[id], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ids returns the IDs of the failed stories, sorted.
+func (e StoryErrors) ids() []string {
+	var ids []string
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DownloadStory downloads story, decrypts it and writes the media to w.
+// If thumbnail is not nil, the story's thumbnail is downloaded and written to it as well.
+func (c *Casper) DownloadStory(story Story, w io.Writer, thumbnail io.Writer) error {
+	return c.DownloadStoryContext(context.Background(), story, w, thumbnail)
+}
+
+// DownloadStoryContext is like DownloadStory but carries ctx to every request it makes.
+func (c *Casper) DownloadStoryContext(ctx context.Context, story Story, w io.Writer, thumbnail io.Writer) error {
+	res, err := c.openStoryBlob(ctx, story.MediaURL, "/bq/story_blob", "/bq/auth_story_blob", story)
+	if err != nil {
+		return err
+	}
+	err = media.DecryptStory(w, res.Body, story.MediaKey, story.MediaIv)
+	res.Body.Close()
+	if err != nil || thumbnail == nil {
+		return err
+	}
+
+	res, err = c.openStoryBlob(ctx, story.ThumbnailURL, "/bq/story_thumbnail", "/bq/auth_story_thumbnail", story)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return media.DecryptStory(thumbnail, res.Body, story.MediaKey, story.ThumbnailIv)
+}
+
+// ArchiveFriendStories fetches updates once and archives every friend story into dir.
+// Each story is written to dir/<username>/<story id> as media, a thumbnail when there is one
+// and a JSON sidecar holding a StoryArchive. Stories archived before are skipped.
+// A story that fails to archive does not stop the others, the failures are returned as StoryErrors.
+func (c *Casper) ArchiveFriendStories(dir string) error {
+	return c.ArchiveFriendStoriesContext(context.Background(), dir)
+}
+
+// ArchiveFriendStoriesContext is like ArchiveFriendStories but carries ctx to every request it makes.
+func (c *Casper) ArchiveFriendStoriesContext(ctx context.Context, dir string) error {
+	updates, err := c.UpdatesContext(ctx)
+	if err != nil {
+		return err
+	}
+	errs := StoryErrors{}
+	for _, friend := range updates.StoriesResponse.FriendStories {
+		friendDir := filepath.Join(dir, safeFileName(friend.Username))
+		err := os.MkdirAll(friendDir, 0755)
+		for _, s := range friend.Stories {
+			if err != nil {
+				errs[s.Story.ID] = err
+				continue
+			}
+			archive := StoryArchive{
+				Username:    friend.Username,
+				DisplayName: friend.DisplayName,
+				Viewed:      s.Viewed,
+				Story:       s.Story,
+			}
+			if err := c.archiveStory(ctx, friendDir, archive); err != nil {
+				errs[s.Story.ID] = err
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// archiveStory writes the story of archive, its thumbnail and its sidecar into dir.
+func (c *Casper) archiveStory(ctx context.Context, dir string, archive StoryArchive) error {
+	name := safeFileName(archive.Story.ID)
+	sidecar := filepath.Join(dir, name+".json")
+	if _, err := os.Stat(sidecar); err == nil {
+		return nil
+	}
+
+	archive.MediaFile = name + storyExtension(archive.Story)
+	files := []string{filepath.Join(dir, archive.MediaFile)}
+	if archive.Story.ThumbnailIv != "" {
+		archive.ThumbnailFile = name + "_thumbnail.jpg"
+		files = append(files, filepath.Join(dir, archive.ThumbnailFile))
+	}
+	var writers []*os.File
+	var err error
+	for _, filename := range files {
+		var f *os.File
+		f, err = os.Create(filename)
+		if err != nil {
+			break
+		}
+		writers = append(writers, f)
+	}
+	if err == nil {
+		var thumbnail io.Writer
+		if len(writers) > 1 {
+			thumbnail = writers[1]
+		}
+		err = c.DownloadStoryContext(ctx, archive.Story, writers[0], thumbnail)
+	}
+	// Close every file before the sidecar is written, so a file that failed to write is never marked archived.
+	for _, f := range writers {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		for _, f := range writers {
+			os.Remove(f.Name())
+		}
+		return fmt.Errorf("casper: archiving story %s: %w", archive.Story.ID, err)
+	}
+
+	archive.ArchivedAt = time.Now().Unix()
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(sidecar, data)
+}
+
+// openStoryBlob opens the encrypted blob of story at blobURL. Stories that need auth are fetched
+// from the Casper signed authEndpoint instead. Without a blobURL, endpoint is used.
+func (c *Casper) openStoryBlob(ctx context.Context, blobURL, endpoint, authEndpoint string, story Story) (*http.Response, error) {
+	if story.NeedsAuth {
+		return c.open(ctx, authEndpoint, map[string]string{
+			"story_id": story.MediaID,
+		})
+	}
+	if blobURL == "" {
+		blobURL = endpoint + "?story_id=" + url.QueryEscape(story.MediaID)
+	}
+	if strings.HasPrefix(blobURL, "/") {
+		blobURL = c.snapchatURL() + blobURL
+	}
+	var res *http.Response
+	err := c.retry(ctx, func() error {
+		req, err := http.NewRequest("GET", blobURL, nil)
+		if err != nil {
+			return err
+		}
+		s := Snapchat{
+			CasperClient: c,
+		}
+		res, err = s.open(req.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// storyExtension returns the file extension matching the media of story.
+func storyExtension(story Story) string {
+	switch {
+	case story.Zipped:
+		return ".zip"
+	case story.MediaType == MediaImage:
+		return ".jpg"
+	}
+	return ".mp4"
+}
+
+// safeFileName replaces path separators in name so it can be used as a single file name.
+func safeFileName(name string) string {
+	name = strings.Replace(name, "/", "_", -1)
+	name = strings.Replace(name, string(os.PathSeparator), "_", -1)
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to filename,
+// so filename never holds partial data.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,214 @@
+package casper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hako/casper/media"
+)
+
+var testStory = Story{
+	ID:          "test_friend~1457484764",
+	MediaID:     "test_media_id",
+	MediaKey:    "K34VFiiu0qar9xWICc9PPA==",
+	MediaIv:     "AAECAwQFBgcICQoLDA0ODw==",
+	ThumbnailIv: "Dw4NDAsKCQgHBgUEAwIBAA==",
+	MediaType:   MediaImage,
+}
+
+// newTestStoryHandler returns a handler serving updates holding story as the only friend story,
+// along with its media and thumbnail encrypted with the story key.
+func newTestStoryHandler(t *testing.T, story Story, mediaData, thumbnailData []byte) http.HandlerFunc {
+	var encryptedMedia, encryptedThumbnail bytes.Buffer
+	if err := media.EncryptStory(&encryptedMedia, bytes.NewReader(mediaData), story.MediaKey, story.MediaIv); err != nil {
+		t.Fatal(err)
+	}
+	if err := media.EncryptStory(&encryptedThumbnail, bytes.NewReader(thumbnailData), story.MediaKey, story.ThumbnailIv); err != nil {
+		t.Fatal(err)
+	}
+	storyJSON, err := json.Marshal(story)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/loq/all_updates":
+			rw.Write([]byte(`{"stories_response":{"friend_stories":[{"username":"test_friend","display_name":"Test Friend","stories":[{"viewed":true,"story":` + string(storyJSON) + `}]}]}}`))
+		case "/bq/story_blob":
+			rw.Write(encryptedMedia.Bytes())
+		case "/bq/story_thumbnail":
+			rw.Write(encryptedThumbnail.Bytes())
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// Test DownloadStory.
+func TestDownloadStory(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0test_story_data")
+	thumbnail := []byte("\xff\xd8\xff\xe0test_thumbnail_data")
+
+	testCasperClient, closeServers := newTestCasper(newTestStoryHandler(t, testStory, jpeg, thumbnail))
+	defer closeServers()
+
+	var paramTests = []struct {
+		thumbnail bool
+	}{
+		{false},
+		{true},
+	}
+
+	for _, test := range paramTests {
+		var downloaded, downloadedThumbnail bytes.Buffer
+		var err error
+		if test.thumbnail {
+			err = testCasperClient.DownloadStory(testStory, &downloaded, &downloadedThumbnail)
+		} else {
+			err = testCasperClient.DownloadStory(testStory, &downloaded, nil)
+		}
+		if err != nil {
+			t.Fatalf("DownloadStory(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", testStory.ID, "<nil>", err)
+		}
+		if !bytes.Equal(downloaded.Bytes(), jpeg) {
+			t.Errorf("DownloadStory(%q) failed test. \n\n\rWant: \n\r\"%x\" \n\rGot: \n\r\"%x\" \n\n", testStory.ID, jpeg, downloaded.Bytes())
+		}
+		if test.thumbnail && !bytes.Equal(downloadedThumbnail.Bytes(), thumbnail) {
+			t.Errorf("DownloadStory(%q) failed test. \n\n\rWant: \n\r\"%x\" \n\rGot: \n\r\"%x\" \n\n", testStory.ID, thumbnail, downloadedThumbnail.Bytes())
+		}
+	}
+}
+
+// Test ArchiveFriendStories.
+func TestArchiveFriendStories(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0test_story_data")
+	thumbnail := []byte("\xff\xd8\xff\xe0test_thumbnail_data")
+
+	testCasperClient, closeServers := newTestCasper(newTestStoryHandler(t, testStory, jpeg, thumbnail))
+	defer closeServers()
+
+	dir, err := ioutil.TempDir("", "casper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := testCasperClient.ArchiveFriendStories(dir); err != nil {
+		t.Fatalf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", dir, "<nil>", err)
+	}
+
+	storyDir := filepath.Join(dir, "test_friend")
+	var paramTests = []struct {
+		file        string
+		expectation []byte
+	}{
+		{"test_friend~1457484764.jpg", jpeg},
+		{"test_friend~1457484764_thumbnail.jpg", thumbnail},
+	}
+
+	for _, test := range paramTests {
+		result, err := ioutil.ReadFile(filepath.Join(storyDir, test.file))
+		if err != nil {
+			t.Fatalf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", dir, test.file, err)
+		}
+		if !bytes.Equal(result, test.expectation) {
+			t.Errorf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%x\" \n\rGot: \n\r\"%x\" \n\n", dir, test.expectation, result)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(storyDir, "test_friend~1457484764.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive StoryArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		t.Fatal(err)
+	}
+	if archive.DisplayName != "Test Friend" || !archive.Viewed || archive.Story.MediaID != testStory.MediaID {
+		t.Errorf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", dir, "Test Friend", data)
+	}
+
+	// A second run finds the sidecar and leaves the archived story alone.
+	os.Remove(filepath.Join(storyDir, "test_friend~1457484764.jpg"))
+	if err := testCasperClient.ArchiveFriendStories(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(storyDir, "test_friend~1457484764.jpg")); !os.IsNotExist(err) {
+		t.Errorf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", dir, "skipped story", err)
+	}
+}
+
+// Test ArchiveFriendStories when a download fails.
+func TestArchiveFriendStoriesFailure(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0test_story_data")
+	thumbnail := []byte("\xff\xd8\xff\xe0test_thumbnail_data")
+
+	otherStory := testStory
+	otherStory.ID = "test_other_friend~1457484765"
+	otherStory.ThumbnailIv = ""
+	otherStoryJSON, err := json.Marshal(otherStory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newTestStoryHandler(t, testStory, jpeg, thumbnail)
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/loq/all_updates":
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			updates := strings.Replace(rec.Body.String(), `]}}`, `,{"username":"test_other_friend","stories":[{"story":`+string(otherStoryJSON)+`}]}]}}`, 1)
+			rw.Write([]byte(updates))
+		case "/bq/story_thumbnail":
+			rw.WriteHeader(http.StatusNotFound)
+		default:
+			handler(rw, req)
+		}
+	})
+	defer closeServers()
+
+	dir, err := ioutil.TempDir("", "casper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = testCasperClient.ArchiveFriendStories(dir)
+	var storyErrs StoryErrors
+	if !errors.As(err, &storyErrs) || len(storyErrs) != 1 || storyErrs[testStory.ID] == nil || !errors.Is(err, ErrSnapchat) {
+		t.Errorf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", dir, "error for "+testStory.ID, err)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(dir, "test_friend"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%d files\" \n\n", dir, "no files", len(files))
+	}
+	for _, file := range []string{"test_other_friend~1457484765.jpg", "test_other_friend~1457484765.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "test_other_friend", file)); err != nil {
+			t.Errorf("ArchiveFriendStories(%q) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%v\" \n\n", dir, file, err)
+		}
+	}
+}
+
+// Test DownloadStory when Snapchat answers with an error payload.
+func TestDownloadStoryError(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"logged": false, "message": "Story not found", "status": -100}`))
+	})
+	defer closeServers()
+
+	var downloaded bytes.Buffer
+	err := testCasperClient.DownloadStory(testStory, &downloaded, nil)
+	if !errors.Is(err, ErrSnapchat) {
+		t.Errorf("DownloadStory(%q) failed test. \n\n\rWant: \n\r\"%v\" \n\rGot: \n\r\"%v\" \n\n", testStory.ID, ErrSnapchat, err)
+	}
+}
@@ -0,0 +1,218 @@
+package casper
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// FriendDeleted is the type of a friend removed since the last sync, sent in partial friend syncs.
+const FriendDeleted = 3
+
+// SyncState holds what UpdatesSince needs to fetch only what changed since its last call:
+// the friends sync token and the merged Snapshot of every update so far.
+// The zero value fetches everything. A SyncState can be saved as JSON between runs.
+type SyncState struct {
+	FriendsSyncToken string  `json:"friends_sync_token"`
+	Snapshot         Updates `json:"snapshot"`
+}
+
+// UpdatesChanges lists what changed between two calls to UpdatesSince.
+type UpdatesChanges struct {
+	AddedFriends     []FriendInfo
+	RemovedFriends   []string
+	NewStories       []Story
+	NewConversations []Conversation
+}
+
+// UpdatesSince fetches the updates that happened since state was last synced, merges them into state.Snapshot
+// and returns the merged updates along with what changed.
+// Sections Snapchat leaves out of its response because they did not change are kept from the snapshot.
+// Only the friends sync token is sent: Snapchat's responses hold no checksums to send back in checksums_dict,
+// so conversations and stories are fetched in full every time.
+// A nil state fetches everything, like the zero value, and is not updated.
+func (c *Casper) UpdatesSince(state *SyncState) (Updates, UpdatesChanges, error) {
+	return c.UpdatesSinceContext(context.Background(), state)
+}
+
+// UpdatesSinceContext is like UpdatesSince but carries ctx to every request it makes.
+func (c *Casper) UpdatesSinceContext(ctx context.Context, state *SyncState) (Updates, UpdatesChanges, error) {
+	if state == nil {
+		state = &SyncState{}
+	}
+	extra := make(map[string]string)
+	if state.FriendsSyncToken != "" {
+		friendsRequest, err := json.Marshal(map[string]string{
+			"friends_sync_token": state.FriendsSyncToken,
+		})
+		if err != nil {
+			return Updates{}, UpdatesChanges{}, err
+		}
+		extra["friends_request"] = string(friendsRequest)
+	}
+	res, err := c.Call(ctx, "/loq/all_updates", extra)
+	if err != nil {
+		return Updates{}, UpdatesChanges{}, err
+	}
+	var delta Updates
+	if err := c.decode("/loq/all_updates", res.Body, &delta); err != nil {
+		return Updates{}, UpdatesChanges{}, err
+	}
+
+	merged, changes := mergeUpdates(state.Snapshot, delta, res.Body)
+	state.Snapshot = merged
+	if merged.FriendsResponse.FriendsSyncToken != "" {
+		state.FriendsSyncToken = merged.FriendsResponse.FriendsSyncToken
+	}
+	return merged, changes, nil
+}
+
+// mergeUpdates merges delta, decoded from body, into snapshot and returns the result and what changed.
+func mergeUpdates(snapshot, delta Updates, body []byte) (Updates, UpdatesChanges) {
+	var changes UpdatesChanges
+	merged := overlaySections(snapshot, delta, body)
+
+	// Friends are either sent in full or, for a partial sync, only those added, changed or deleted.
+	previousFriends := make(map[string]bool)
+	for _, friend := range snapshot.FriendsResponse.Friends {
+		previousFriends[friend.Name] = true
+	}
+	if delta.FriendsResponse.Friends == nil && delta.FriendsResponse.FriendsSyncType == "" {
+		merged.FriendsResponse.Friends = snapshot.FriendsResponse.Friends
+	} else {
+		friends := delta.FriendsResponse.Friends
+		if delta.FriendsResponse.FriendsSyncType == "partial" {
+			friends = mergeFriends(snapshot.FriendsResponse.Friends, delta.FriendsResponse.Friends)
+		}
+		current := make(map[string]bool)
+		var kept []FriendInfo
+		for _, friend := range friends {
+			if friend.Type == FriendDeleted {
+				continue
+			}
+			current[friend.Name] = true
+			kept = append(kept, friend)
+			if !previousFriends[friend.Name] {
+				changes.AddedFriends = append(changes.AddedFriends, friend)
+			}
+		}
+		for _, friend := range snapshot.FriendsResponse.Friends {
+			if !current[friend.Name] {
+				changes.RemovedFriends = append(changes.RemovedFriends, friend.Name)
+			}
+		}
+		merged.FriendsResponse.Friends = kept
+	}
+
+	// Friend stories are either sent in full or, as a delta, only for friends whose stories changed.
+	previousStories := make(map[string]bool)
+	for _, group := range snapshot.StoriesResponse.FriendStories {
+		for _, s := range group.Stories {
+			previousStories[s.Story.ID] = true
+		}
+	}
+	if delta.StoriesResponse.FriendStories == nil {
+		merged.StoriesResponse.FriendStories = snapshot.StoriesResponse.FriendStories
+	} else {
+		if delta.StoriesResponse.FriendStoriesDelta {
+			merged.StoriesResponse.FriendStories = mergeFriendStories(snapshot.StoriesResponse.FriendStories, delta.StoriesResponse.FriendStories)
+		}
+		for _, group := range merged.StoriesResponse.FriendStories {
+			for _, s := range group.Stories {
+				if !previousStories[s.Story.ID] {
+					changes.NewStories = append(changes.NewStories, s.Story)
+				}
+			}
+		}
+	}
+
+	// Conversations Snapchat sends replace those with the same ID, the others are kept.
+	previousConversations := make(map[string]bool)
+	for _, conversation := range snapshot.ConversationsResponse {
+		previousConversations[conversation.ID] = true
+	}
+	if delta.ConversationsResponse == nil {
+		merged.ConversationsResponse = snapshot.ConversationsResponse
+	} else {
+		merged.ConversationsResponse = mergeConversations(snapshot.ConversationsResponse, delta.ConversationsResponse)
+		for _, conversation := range delta.ConversationsResponse {
+			if !previousConversations[conversation.ID] {
+				changes.NewConversations = append(changes.NewConversations, conversation)
+			}
+		}
+	}
+	return merged, changes
+}
+
+// mergeFriends returns friends with every friend of delta added or replacing the friend of the same name.
+func mergeFriends(friends, delta []FriendInfo) []FriendInfo {
+	index := make(map[string]int)
+	merged := append([]FriendInfo(nil), friends...)
+	for i, friend := range merged {
+		index[friend.Name] = i
+	}
+	for _, friend := range delta {
+		if i, ok := index[friend.Name]; ok {
+			merged[i] = friend
+			continue
+		}
+		index[friend.Name] = len(merged)
+		merged = append(merged, friend)
+	}
+	return merged
+}
+
+// mergeFriendStories returns groups with every group of delta added or replacing the group of the same friend.
+func mergeFriendStories(groups, delta []FriendStoryGroup) []FriendStoryGroup {
+	index := make(map[string]int)
+	merged := append([]FriendStoryGroup(nil), groups...)
+	for i, group := range merged {
+		index[group.Username] = i
+	}
+	for _, group := range delta {
+		if i, ok := index[group.Username]; ok {
+			merged[i] = group
+			continue
+		}
+		index[group.Username] = len(merged)
+		merged = append(merged, group)
+	}
+	return merged
+}
+
+// mergeConversations returns conversations with every conversation of delta added or replacing the one of the same ID.
+func mergeConversations(conversations, delta []Conversation) []Conversation {
+	index := make(map[string]int)
+	merged := append([]Conversation(nil), conversations...)
+	for i, conversation := range merged {
+		index[conversation.ID] = i
+	}
+	for _, conversation := range delta {
+		if i, ok := index[conversation.ID]; ok {
+			merged[i] = conversation
+			continue
+		}
+		index[conversation.ID] = len(merged)
+		merged = append(merged, conversation)
+	}
+	return merged
+}
+
+// overlaySections returns snapshot with every top-level section held by body replaced by the one of delta.
+func overlaySections(snapshot, delta Updates, body []byte) Updates {
+	merged := snapshot
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(body, &sections); err != nil {
+		return merged
+	}
+	fields := jsonFields(reflect.TypeOf(merged))
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	deltaValue := reflect.ValueOf(delta)
+	for name := range sections {
+		if f, ok := fields[strings.ToLower(name)]; ok {
+			mergedValue.FieldByIndex(f.Index).Set(deltaValue.FieldByIndex(f.Index))
+		}
+	}
+	return merged
+}
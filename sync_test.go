@@ -0,0 +1,100 @@
+package casper
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// Test UpdatesSince.
+func TestUpdatesSince(t *testing.T) {
+	responses := []string{
+		`{
+			"updates_response": {"username": "test_api_user", "score": 42},
+			"friends_response": {"friends_sync_token": "test_sync_token_1", "friends_sync_type": "full", "friends": [{"name": "test_friend_a"}, {"name": "test_friend_b"}]},
+			"stories_response": {"friend_stories": [{"username": "test_friend_a", "stories": [{"story": {"id": "test_story_1"}}]}]},
+			"conversations_response": [{"id": "test_conversation_1"}]
+		}`,
+		`{
+			"friends_response": {"friends_sync_token": "test_sync_token_2", "friends_sync_type": "partial", "friends": [{"name": "test_friend_b", "type": 3}, {"name": "test_friend_c"}]},
+			"stories_response": {"friend_stories_delta": true, "friend_stories": [{"username": "test_friend_c", "stories": [{"story": {"id": "test_story_2"}}]}]},
+			"conversations_response": [{"id": "test_conversation_2"}]
+		}`,
+		`{}`,
+	}
+	var requests []*http.Request
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		requests = append(requests, req)
+		rw.Write([]byte(responses[len(requests)-1]))
+	})
+	defer closeServers()
+
+	var paramTests = []struct {
+		friendsRequest string
+		expectation    string
+		friends        string
+		stories        string
+	}{
+		{"", "added [test_friend_a test_friend_b] removed [] stories [test_story_1] conversations [test_conversation_1]", "[test_friend_a test_friend_b]", "[test_story_1]"},
+		{`{"friends_sync_token":"test_sync_token_1"}`, "added [test_friend_c] removed [test_friend_b] stories [test_story_2] conversations [test_conversation_2]", "[test_friend_a test_friend_c]", "[test_story_1 test_story_2]"},
+		{`{"friends_sync_token":"test_sync_token_2"}`, "added [] removed [] stories [] conversations []", "[test_friend_a test_friend_c]", "[test_story_1 test_story_2]"},
+	}
+
+	var state SyncState
+	for i, test := range paramTests {
+		updates, changes, err := testCasperClient.UpdatesSince(&state)
+		if err != nil {
+			t.Fatalf("UpdatesSince(%d) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", i, "<nil>", err)
+		}
+		if result := requests[i].FormValue("friends_request"); result != test.friendsRequest {
+			t.Errorf("UpdatesSince(%d) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", i, test.friendsRequest, result)
+		}
+		if _, ok := requests[i].Form["checksums_dict"]; ok {
+			t.Errorf("UpdatesSince(%d) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", i, "", requests[i].FormValue("checksums_dict"))
+		}
+		if updates.UpdatesResponse.Username != "test_api_user" || updates.UpdatesResponse.Score != 42 {
+			t.Errorf("UpdatesSince(%d) failed test. \n\n\rWant: \n\r\"%s %d\" \n\rGot: \n\r\"%s %d\" \n\n", i, "test_api_user", 42, updates.UpdatesResponse.Username, updates.UpdatesResponse.Score)
+		}
+
+		var added, stories, conversations, friends, allStories []string
+		for _, friend := range changes.AddedFriends {
+			added = append(added, friend.Name)
+		}
+		for _, story := range changes.NewStories {
+			stories = append(stories, story.ID)
+		}
+		for _, conversation := range changes.NewConversations {
+			conversations = append(conversations, conversation.ID)
+		}
+		result := fmt.Sprintf("added %v removed %v stories %v conversations %v", added, changes.RemovedFriends, stories, conversations)
+		if result != test.expectation {
+			t.Errorf("UpdatesSince(%d) failed test. \n\n\rWant: \n\r\"%s\" \n\rGot: \n\r\"%s\" \n\n", i, test.expectation, result)
+		}
+
+		for _, friend := range updates.FriendsResponse.Friends {
+			friends = append(friends, friend.Name)
+		}
+		for _, group := range updates.StoriesResponse.FriendStories {
+			for _, s := range group.Stories {
+				allStories = append(allStories, s.Story.ID)
+			}
+		}
+		if fmt.Sprint(friends) != test.friends || fmt.Sprint(allStories) != test.stories {
+			t.Errorf("UpdatesSince(%d) failed test. \n\n\rWant: \n\r\"%s %s\" \n\rGot: \n\r\"%v %v\" \n\n", i, test.friends, test.stories, friends, allStories)
+		}
+	}
+}
+
+// Test UpdatesSince with a nil SyncState.
+func TestUpdatesSinceNil(t *testing.T) {
+	testCasperClient, closeServers := newTestCasper(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"friends_response": {"friends": [{"name": "test_friend_a"}]}}`))
+	})
+	defer closeServers()
+
+	_, changes, err := testCasperClient.UpdatesSince(nil)
+	if err != nil || len(changes.AddedFriends) != 1 {
+		t.Errorf("UpdatesSince(%v) failed test. \n\n\rWant: \n\r\"%d %v\" \n\rGot: \n\r\"%d %v\" \n\n", nil, 1, nil, len(changes.AddedFriends), err)
+	}
+}